@@ -0,0 +1,466 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/percipia/eslgo/command"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoHealthyNodes - Returned by Cluster when there is no connected node to route to
+var ErrNoHealthyNodes = errors.New("no healthy FreeSWITCH nodes")
+
+// ClusterEventListener - Called for events received from any node in the cluster along with the node that sent it
+type ClusterEventListener func(node *ClusterNode, event *Event)
+
+// ClusterOptions - Used to dial inbound connections to multiple FreeSWITCH nodes
+type ClusterOptions struct {
+	InboundOptions               // Used when dialing every node. OnDisconnect is called for every node disconnect
+	Strategy       Strategy      // How commands are routed to nodes. Defaults to RoundRobinStrategy
+	HealthInterval time.Duration // How often nodes are checked with "api status" and disconnected nodes are re-dialed
+	Events         []string      // Events to subscribe to on every node, CHANNEL_CREATE and CHANNEL_DESTROY are always subscribed to track channels
+}
+
+// DefaultClusterOptions - The default options used for creating a cluster
+var DefaultClusterOptions = ClusterOptions{
+	InboundOptions: DefaultInboundOptions,
+	HealthInterval: 10 * time.Second,
+}
+
+// Cluster - Inbound connections to multiple FreeSWITCH nodes with health checks, command routing and a merged event stream
+type Cluster struct {
+	opts              ClusterOptions
+	nodes             []*ClusterNode
+	runningContext    context.Context
+	stopFunc          func()
+	logger            *slog.Logger
+	metrics           Metrics
+	eventListenerLock sync.RWMutex
+	eventListeners    map[string]map[string]ClusterEventListener
+}
+
+// ClusterNode - A single FreeSWITCH node in a Cluster
+type ClusterNode struct {
+	Address        string
+	mutex          sync.RWMutex
+	conn           *Conn
+	connected      bool
+	activeChannels int
+	hostname       string
+	coreUUID       string
+	channels       map[string]struct{}
+}
+
+// Strategy - Picks which node a command is routed to. nodes only contains healthy nodes and is never empty.
+// channelUUID is the channel the command targets, empty if it does not target a channel.
+type Strategy interface {
+	Pick(nodes []*ClusterNode, channelUUID string) *ClusterNode
+}
+
+// RoundRobinStrategy - Routes commands to each node in turn
+type RoundRobinStrategy struct {
+	next atomic.Uint64
+}
+
+// LeastActiveChannelsStrategy - Routes commands to the node with the fewest active channels as reported by "api status" during health checks
+type LeastActiveChannelsStrategy struct{}
+
+// StickyStrategy - Routes commands targeting a channel to the node that owns the channel, everything else is routed by Fallback
+type StickyStrategy struct {
+	Fallback Strategy // Used for commands not targeting a known channel. Defaults to LeastActiveChannelsStrategy
+}
+
+var activeSessionsRegex = regexp.MustCompile(`(?m)^(\d+) session\(s\) - peak`)
+
+// DialCluster - Connects to all FreeSWITCH nodes at the provided addresses with the default cluster options
+func DialCluster(password string, addresses ...string) (*Cluster, error) {
+	opts := DefaultClusterOptions
+	opts.Password = password
+	return opts.Dial(addresses...)
+}
+
+// Dial - Connects to all FreeSWITCH nodes at the provided addresses. Succeeds as long as one node could be connected, the rest are re-dialed during health checks
+func (opts ClusterOptions) Dial(addresses ...string) (*Cluster, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no addresses specified")
+	}
+	if opts.Strategy == nil {
+		opts.Strategy = &RoundRobinStrategy{}
+	}
+	if opts.HealthInterval <= 0 {
+		opts.HealthInterval = DefaultClusterOptions.HealthInterval
+	}
+	if opts.Context == nil {
+		opts.Context = context.Background()
+	}
+
+	runningContext, stop := context.WithCancel(opts.Context)
+	cluster := &Cluster{
+		opts:           opts,
+		runningContext: runningContext,
+		stopFunc:       stop,
+		logger:         opts.newLogger(),
+		metrics:        opts.Metrics,
+		eventListeners: make(map[string]map[string]ClusterEventListener),
+	}
+	if cluster.metrics == nil {
+		cluster.metrics = NilMetrics{}
+	}
+	for _, address := range addresses {
+		cluster.nodes = append(cluster.nodes, &ClusterNode{
+			Address:  address,
+			channels: make(map[string]struct{}),
+		})
+	}
+
+	cluster.checkAll()
+	if len(cluster.healthyNodes()) == 0 {
+		cluster.Close()
+		return nil, ErrNoHealthyNodes
+	}
+
+	go cluster.healthLoop()
+	return cluster, nil
+}
+
+// Nodes - Returns all nodes in the cluster, healthy or not
+func (c *Cluster) Nodes() []*ClusterNode {
+	return c.nodes
+}
+
+// Pick - Returns the node the strategy picks for the channel UUID, or any node if channelUUID is empty
+func (c *Cluster) Pick(channelUUID string) (*ClusterNode, error) {
+	nodes := c.healthyNodes()
+	if len(nodes) == 0 {
+		return nil, ErrNoHealthyNodes
+	}
+	node := c.opts.Strategy.Pick(nodes, channelUUID)
+	if node == nil {
+		return nil, ErrNoHealthyNodes
+	}
+	return node, nil
+}
+
+// SendCommand - Sends the command to the node picked by the strategy. Commands targeting a channel pass the channel UUID to the strategy
+func (c *Cluster) SendCommand(ctx context.Context, cmd command.Command) (*RawResponse, error) {
+	node, err := c.Pick(commandChannelUUID(cmd.BuildMessage()))
+	if err != nil {
+		return nil, err
+	}
+	conn := node.Conn()
+	if conn == nil {
+		return nil, ErrNoHealthyNodes
+	}
+	return conn.SendCommand(ctx, cmd)
+}
+
+// SendCommandChecked - Calls SendCommand and converts -ERR and -USAGE replies into an *ESLError
+func (c *Cluster) SendCommandChecked(ctx context.Context, cmd command.Command) (*RawResponse, error) {
+	response, err := c.SendCommand(ctx, cmd)
+	if err != nil {
+		return response, err
+	}
+	return response, response.Err(cmd)
+}
+
+// OriginateCall - Calls OriginateCall on the node picked by the strategy
+func (c *Cluster) OriginateCall(ctx context.Context, background bool, aLeg, bLeg Leg, vars map[string]string) (*RawResponse, error) {
+	node, err := c.Pick("")
+	if err != nil {
+		return nil, err
+	}
+	conn := node.Conn()
+	if conn == nil {
+		return nil, ErrNoHealthyNodes
+	}
+	return conn.OriginateCall(ctx, background, aLeg, bLeg, vars)
+}
+
+// RegisterEventListener - Registers a new event listener for events from every node for the specified channel UUID(or EventListenAll). Returns the registered listener ID used to remove it.
+func (c *Cluster) RegisterEventListener(channelUUID string, listener ClusterEventListener) string {
+	c.eventListenerLock.Lock()
+	defer c.eventListenerLock.Unlock()
+
+	id := uuid.New().String()
+	if _, ok := c.eventListeners[channelUUID]; ok {
+		c.eventListeners[channelUUID][id] = listener
+	} else {
+		c.eventListeners[channelUUID] = map[string]ClusterEventListener{id: listener}
+	}
+	return id
+}
+
+// RemoveEventListener - Removes the listener for the specified channel UUID with the listener ID returned from RegisterEventListener
+func (c *Cluster) RemoveEventListener(channelUUID string, id string) {
+	c.eventListenerLock.Lock()
+	defer c.eventListenerLock.Unlock()
+
+	if listeners, ok := c.eventListeners[channelUUID]; ok {
+		delete(listeners, id)
+	}
+}
+
+// Close - Stops health checks and gracefully closes the connections to all nodes
+func (c *Cluster) Close() {
+	c.stopFunc()
+	var wait sync.WaitGroup
+	for _, node := range c.nodes {
+		if conn := node.Conn(); conn != nil {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				conn.ExitAndClose()
+			}()
+		}
+	}
+	wait.Wait()
+}
+
+func (c *Cluster) healthyNodes() []*ClusterNode {
+	nodes := make([]*ClusterNode, 0, len(c.nodes))
+	for _, node := range c.nodes {
+		if node.Healthy() {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+func (c *Cluster) healthLoop() {
+	ticker := time.NewTicker(c.opts.HealthInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.checkAll()
+		case <-c.runningContext.Done():
+			return
+		}
+	}
+}
+
+func (c *Cluster) checkAll() {
+	var wait sync.WaitGroup
+	for _, node := range c.nodes {
+		wait.Add(1)
+		go func(node *ClusterNode) {
+			defer wait.Done()
+			c.check(node)
+		}(node)
+	}
+	wait.Wait()
+}
+
+// check - Dials the node if it is not connected, then updates its status with "api status"
+func (c *Cluster) check(node *ClusterNode) {
+	logger := c.logger.With(slog.String(LogKeyRemoteAddr, node.Address))
+	conn := node.Conn()
+	if conn == nil {
+		var err error
+		conn, err = c.connect(node)
+		if err != nil {
+			logger.Warn("Error connecting to cluster node", LogKeyError, err)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.runningContext, c.opts.HealthInterval)
+	response, err := conn.SendCommand(ctx, command.API{Command: "status"})
+	cancel()
+	if err != nil {
+		logger.Warn("Cluster node failed health check", LogKeyError, err)
+		node.disconnected(conn)
+		conn.Close()
+		return
+	}
+	if matches := activeSessionsRegex.FindSubmatch(response.Body); matches != nil {
+		active, _ := strconv.Atoi(string(matches[1]))
+		node.setActiveChannels(active)
+	}
+}
+
+func (c *Cluster) connect(node *ClusterNode) (*Conn, error) {
+	opts := c.opts.InboundOptions
+	opts.OnDisconnect = func() {
+		if conn := node.Conn(); conn != nil && conn.runningContext.Err() != nil {
+			node.disconnected(conn)
+		}
+		if c.opts.OnDisconnect != nil {
+			c.opts.OnDisconnect()
+		}
+	}
+	conn, err := opts.Dial(node.Address)
+	if err != nil {
+		return nil, err
+	}
+	// Channels are tracked in the order the events were sent so a CHANNEL_DESTROY can never be handled before its CHANNEL_CREATE
+	conn.RegisterOrderedEventListener(EventListenAll, node.trackEvent)
+	conn.RegisterEventListener(EventListenAll, func(event *Event) {
+		c.handleEvent(node, event)
+	})
+
+	ctx, cancel := context.WithTimeout(c.runningContext, opts.AuthTimeout)
+	_, err = conn.SendCommand(ctx, command.Event{
+		Format: "plain",
+		Listen: append([]string{"CHANNEL_CREATE", "CHANNEL_DESTROY"}, c.opts.Events...),
+	})
+	cancel()
+	if err != nil {
+		conn.ExitAndClose()
+		return nil, err
+	}
+
+	if c.runningContext.Err() != nil {
+		// The cluster was closed while we were connecting
+		conn.ExitAndClose()
+		return nil, c.runningContext.Err()
+	}
+	if node.connectedTo(conn) {
+		c.metrics.Reconnected(node.Address)
+	}
+	return conn, nil
+}
+
+func (c *Cluster) handleEvent(node *ClusterNode, event *Event) {
+	// Copy the listeners so they can register or remove listeners themselves
+	var listeners []ClusterEventListener
+	c.eventListenerLock.RLock()
+	for _, key := range eventListenerKeys(event) {
+		for _, listener := range c.eventListeners[key] {
+			listeners = append(listeners, listener)
+		}
+	}
+	c.eventListenerLock.RUnlock()
+
+	for _, listener := range listeners {
+		listener(node, event)
+	}
+}
+
+// Conn - Returns the current connection to the node, nil if the node is not connected
+func (n *ClusterNode) Conn() *Conn {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.conn
+}
+
+// Healthy - Returns true if the node is connected and passed its last health check
+func (n *ClusterNode) Healthy() bool {
+	return n.Conn() != nil
+}
+
+// ActiveChannels - Returns the number of active channels reported by the last health check
+func (n *ClusterNode) ActiveChannels() int {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.activeChannels
+}
+
+// Hostname - Returns the FreeSWITCH-Hostname of the node, known once an event has been received from it
+func (n *ClusterNode) Hostname() string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.hostname
+}
+
+// CoreUUID - Returns the Core-UUID of the node, known once an event has been received from it
+func (n *ClusterNode) CoreUUID() string {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	return n.coreUUID
+}
+
+// HasChannel - Returns true if the channel was created on this node and has not been destroyed yet
+func (n *ClusterNode) HasChannel(channelUUID string) bool {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+	_, ok := n.channels[channelUUID]
+	return ok
+}
+
+// connectedTo - Sets the current connection of the node. Returns true if the node was connected before
+func (n *ClusterNode) connectedTo(conn *Conn) bool {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	reconnect := n.connected
+	n.conn = conn
+	n.connected = true
+	return reconnect
+}
+
+// disconnected - Marks the node as disconnected if conn is still its current connection
+func (n *ClusterNode) disconnected(conn *Conn) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.conn == conn {
+		n.conn = nil
+		n.channels = make(map[string]struct{})
+	}
+}
+
+func (n *ClusterNode) setActiveChannels(active int) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	n.activeChannels = active
+}
+
+func (n *ClusterNode) trackEvent(event *Event) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if event.HasHeader("FreeSWITCH-Hostname") {
+		n.hostname = event.GetHeader("FreeSWITCH-Hostname")
+	}
+	if event.HasHeader("Core-UUID") {
+		n.coreUUID = event.GetHeader("Core-UUID")
+	}
+	switch event.GetName() {
+	case "CHANNEL_CREATE":
+		n.channels[event.GetHeader("Unique-ID")] = struct{}{}
+	case "CHANNEL_DESTROY":
+		delete(n.channels, event.GetHeader("Unique-ID"))
+	}
+}
+
+func (r *RoundRobinStrategy) Pick(nodes []*ClusterNode, _ string) *ClusterNode {
+	return nodes[(r.next.Add(1)-1)%uint64(len(nodes))]
+}
+
+func (LeastActiveChannelsStrategy) Pick(nodes []*ClusterNode, _ string) *ClusterNode {
+	least := nodes[0]
+	for _, node := range nodes[1:] {
+		if node.ActiveChannels() < least.ActiveChannels() {
+			least = node
+		}
+	}
+	return least
+}
+
+func (s StickyStrategy) Pick(nodes []*ClusterNode, channelUUID string) *ClusterNode {
+	if len(channelUUID) > 0 {
+		for _, node := range nodes {
+			if node.HasChannel(channelUUID) {
+				return node
+			}
+		}
+	}
+	if s.Fallback == nil {
+		return LeastActiveChannelsStrategy{}.Pick(nodes, channelUUID)
+	}
+	return s.Fallback.Pick(nodes, channelUUID)
+}
@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"github.com/percipia/eslgo/command"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testAPIResponse - Formats an api/response message
+func testAPIResponse(body string) string {
+	return fmt.Sprintf("Content-Type: api/response\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// testEvent - Formats a text/event-plain message with the provided headers
+func testEvent(headers ...string) string {
+	body := strings.Join(headers, "\n") + "\n\n"
+	return fmt.Sprintf("Content-Length: %d\r\nContent-Type: text/event-plain\r\n\r\n%s", len(body), body)
+}
+
+type testClusterNode struct {
+	*testServer
+	lock     sync.Mutex
+	commands []string
+}
+
+func newTestClusterNode(t *testing.T, hostname string, sessions int, channelUUID string) *testClusterNode {
+	node := &testClusterNode{}
+	node.testServer = newTestServer(t, func(command string) string {
+		node.lock.Lock()
+		node.commands = append(node.commands, command)
+		node.lock.Unlock()
+		switch {
+		case command == "api status":
+			return testAPIResponse(fmt.Sprintf("UP 0 years, 0 days\nFreeSWITCH is ready\n%d session(s) - peak 10, last 5min 2\n", sessions))
+		case strings.HasPrefix(command, "event"):
+			return testReply("+OK event listener enabled plain") + testEvent(
+				"Event-Name: CHANNEL_CREATE",
+				"Core-UUID: core-"+hostname,
+				"FreeSWITCH-Hostname: "+hostname,
+				"Unique-ID: "+channelUUID,
+			)
+		case strings.HasPrefix(command, "api"):
+			return testAPIResponse("+OK " + hostname)
+		}
+		return testReply("+OK")
+	})
+	return node
+}
+
+func (n *testClusterNode) received(command string) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	for _, received := range n.commands {
+		if received == command {
+			return true
+		}
+	}
+	return false
+}
+
+func TestCluster(t *testing.T) {
+	busy := newTestClusterNode(t, "busy", 5, "channel-busy")
+	idle := newTestClusterNode(t, "idle", 2, "channel-idle")
+
+	opts := DefaultClusterOptions
+	opts.Logger = NilLogger{}
+	opts.Strategy = StickyStrategy{}
+	opts.HealthInterval = time.Minute
+	cluster, err := opts.Dial(busy.Address(), idle.Address())
+	assert.Nil(t, err)
+	defer cluster.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Channel tracking relies on the CHANNEL_CREATE event sent after subscribing
+	assert.Eventually(t, func() bool {
+		return cluster.Nodes()[0].HasChannel("channel-busy") && cluster.Nodes()[1].HasChannel("channel-idle")
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 5, cluster.Nodes()[0].ActiveChannels())
+	assert.Equal(t, "core-idle", cluster.Nodes()[1].CoreUUID())
+
+	// Sticky routes channel commands to the owning node
+	response, err := cluster.SendCommand(ctx, command.API{Command: "uuid_kill", Arguments: "channel-busy"})
+	assert.Nil(t, err)
+	assert.Equal(t, "+OK busy", response.GetReply())
+	assert.True(t, busy.received("api uuid_kill channel-busy"))
+
+	// Everything else goes to the node with the least active channels
+	response, err = cluster.SendCommand(ctx, command.API{Command: "show", Arguments: "channels"})
+	assert.Nil(t, err)
+	assert.Equal(t, "+OK idle", response.GetReply())
+}
+
+func TestCluster_Events(t *testing.T) {
+	first := newTestClusterNode(t, "first", 0, "channel-first")
+
+	events := make(chan string, 1)
+	opts := DefaultClusterOptions
+	opts.Logger = NilLogger{}
+	cluster, err := opts.Dial(first.Address())
+	assert.Nil(t, err)
+	defer cluster.Close()
+
+	// The listener is registered after dial, so ask for another subscription to receive an event
+	cluster.RegisterEventListener("channel-first", func(node *ClusterNode, event *Event) {
+		events <- node.Hostname() + " " + event.GetName()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = cluster.SendCommand(ctx, command.Event{Format: "plain", Listen: []string{"CHANNEL_CREATE"}})
+	assert.Nil(t, err)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "first CHANNEL_CREATE", event)
+	case <-ctx.Done():
+		t.Fatal("no event received")
+	}
+}
+
+func TestCluster_ChannelTrackingOrder(t *testing.T) {
+	server := newTestServer(t, func(command string) string {
+		switch {
+		case command == "api status":
+			return testAPIResponse("UP 0 years, 0 days\nFreeSWITCH is ready\n0 session(s) - peak 10, last 5min 2\n")
+		case strings.HasPrefix(command, "event"):
+			// Channels created and destroyed right away must not be left behind as phantom channels
+			events := testReply("+OK event listener enabled plain")
+			for i := 0; i < 100; i++ {
+				channelUUID := fmt.Sprintf("Unique-ID: channel-%d", i)
+				events += testEvent("Event-Name: CHANNEL_CREATE", channelUUID) + testEvent("Event-Name: CHANNEL_DESTROY", channelUUID)
+			}
+			return events + testEvent("Event-Name: CHANNEL_CREATE", "Unique-ID: channel-last")
+		}
+		return testReply("+OK")
+	})
+
+	opts := DefaultClusterOptions
+	opts.Logger = NilLogger{}
+	opts.HealthInterval = time.Minute
+	cluster, err := opts.Dial(server.Address())
+	assert.Nil(t, err)
+	defer cluster.Close()
+
+	node := cluster.Nodes()[0]
+	assert.Eventually(t, func() bool {
+		return node.HasChannel("channel-last")
+	}, 5*time.Second, time.Millisecond)
+	node.mutex.RLock()
+	defer node.mutex.RUnlock()
+	assert.Equal(t, map[string]struct{}{"channel-last": {}}, node.channels)
+}
+
+func TestRoundRobinStrategy_Pick(t *testing.T) {
+	nodes := []*ClusterNode{{Address: "a"}, {Address: "b"}}
+	strategy := &RoundRobinStrategy{}
+	assert.Equal(t, "a", strategy.Pick(nodes, "").Address)
+	assert.Equal(t, "b", strategy.Pick(nodes, "").Address)
+	assert.Equal(t, "a", strategy.Pick(nodes, "").Address)
+}
@@ -0,0 +1,163 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/percipia/eslgo"
+	"github.com/percipia/eslgo/command"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errDisconnected - FreeSWITCH went away, or the liveness check declared the connection dead
+var errDisconnected = errors.New("disconnected from FreeSWITCH")
+
+// bridge - Keeps a connection to FreeSWITCH and hands every received event to the forwarders of the sinks that want it
+type bridge struct {
+	cfg        Config
+	forwarders []*forwarder
+	events     map[string]bool // Subscribed event names, upper case
+	custom     map[string]bool // Subscribed CUSTOM subclasses, upper case
+	log        *slog.Logger
+}
+
+// newBridge - Creates the bridge with a sink for every sink in the config, in the same order
+func newBridge(cfg Config, sinks []Sink, logger *slog.Logger) (*bridge, error) {
+	b := &bridge{cfg: cfg, events: make(map[string]bool), custom: make(map[string]bool), log: logger}
+	for _, name := range cfg.Events {
+		b.events[strings.ToUpper(name)] = true
+	}
+	for _, subclass := range cfg.Custom {
+		b.custom[strings.ToUpper(subclass)] = true
+	}
+	for i, sink := range sinks {
+		f, err := newForwarder(cfg.Sinks[i], sink, cfg.SpoolDir, logger)
+		if err != nil {
+			return nil, err
+		}
+		b.forwarders = append(b.forwarders, f)
+	}
+	return b, nil
+}
+
+// run - Forwards events until the context is done, reconnecting with backoff whenever the connection is lost.
+// Returns once pending events were sent or spooled
+func (b *bridge) run(ctx context.Context) {
+	var wait sync.WaitGroup
+	for _, f := range b.forwarders {
+		wait.Add(1)
+		go func(f *forwarder) {
+			defer wait.Done()
+			f.run(ctx)
+		}(f)
+	}
+
+	attempt := 0
+	for ctx.Err() == nil {
+		connected, err := b.session(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+		if connected {
+			attempt = 0
+		}
+		delay := b.cfg.Reconnect.delay(attempt)
+		attempt++
+		b.log.Warn("Connection to FreeSWITCH lost, reconnecting", "address", b.cfg.Address, "error", err, "delay", delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}
+	wait.Wait()
+}
+
+// session - Connects, subscribes and waits for the connection to end. Returns true if the connection was established
+func (b *bridge) session(ctx context.Context) (bool, error) {
+	disconnected := make(chan struct{})
+	opts := eslgo.DefaultInboundOptions
+	opts.Context = ctx
+	opts.LogHandler = b.log.Handler()
+	opts.Password = b.cfg.Password
+	// Dial makes sure this is only called once
+	opts.OnDisconnect = func() {
+		close(disconnected)
+	}
+	switch b.cfg.Liveness {
+	case "probe":
+		opts.Liveness = eslgo.LivenessOptions{Mode: eslgo.LivenessProbe, Interval: time.Duration(b.cfg.LivenessInterval)}
+	case "heartbeat":
+		opts.Liveness = eslgo.LivenessOptions{Mode: eslgo.LivenessHeartbeat, Interval: time.Duration(b.cfg.LivenessInterval)}
+	}
+
+	conn, err := opts.Dial(b.cfg.Address)
+	if err != nil {
+		return false, err
+	}
+	defer conn.ExitAndClose()
+	conn.RegisterOrderedEventListener(eslgo.EventListenAll, b.forward)
+
+	if err = b.subscribe(ctx, conn); err != nil {
+		return true, err
+	}
+	b.log.Info("Connected to FreeSWITCH", "address", b.cfg.Address)
+
+	select {
+	case <-disconnected:
+		return true, errDisconnected
+	case <-ctx.Done():
+		return true, ctx.Err()
+	}
+}
+
+// subscribe - Sets up the filters before subscribing so no unwanted events are sent in between
+func (b *bridge) subscribe(ctx context.Context, conn *eslgo.Conn) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	for _, filter := range b.cfg.Filters {
+		if _, err := conn.SendCommandChecked(ctx, command.Filter{EventHeader: filter.Header, FilterValue: filter.Value}); err != nil {
+			return err
+		}
+	}
+	// Events are subscribed in the json format so they are forwarded exactly as FreeSWITCH sent them
+	_, err := conn.SendCommandChecked(ctx, command.Event{Format: "json", Listen: b.cfg.Events, Custom: b.cfg.Custom})
+	return err
+}
+
+// subscribed - Returns true if the event is one of the configured events or CUSTOM subclasses
+func (b *bridge) subscribed(name, subclass string) bool {
+	return b.events["ALL"] || b.events[name] || (name == "CUSTOM" && b.custom[subclass])
+}
+
+// forward - Queues the event for the sinks that want it
+func (b *bridge) forward(event *eslgo.Event) {
+	name := strings.ToUpper(event.GetName())
+	if event.JSON == nil {
+		b.log.Warn("Dropping event that was not received as json", "event", name)
+		return
+	}
+	subclass := strings.ToUpper(event.GetHeader("Event-Subclass"))
+	if !b.subscribed(name, subclass) {
+		// Events we did not ask for, e.g. the HEARTBEAT events of heartbeat liveness
+		return
+	}
+	for _, f := range b.forwarders {
+		if f.wants(name, subclass) {
+			f.enqueue(event.JSON)
+		}
+	}
+}
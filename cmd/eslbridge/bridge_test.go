@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/percipia/eslgo"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testEvent - Formats a text/event-json message
+func testEvent(event string) string {
+	return fmt.Sprintf("Content-Type: text/event-json\nContent-Length: %d\n\n%s", len(event), event)
+}
+
+// fakeFreeSWITCH - Accepts ESL connections, sending the event of the connection once subscribed and closing the connection afterwards
+func fakeFreeSWITCH(t *testing.T, events ...string) (string, func() []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	var lock sync.Mutex
+	var commands []string
+	go func() {
+		for connection := 0; ; connection++ {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(c net.Conn, event string) {
+				defer c.Close()
+				reader := textproto.NewReader(bufio.NewReader(c))
+				_, _ = c.Write([]byte("Content-Type: auth/request\n\n"))
+				for {
+					line, err := reader.ReadLine()
+					if err != nil {
+						return
+					}
+					if _, err = reader.ReadMIMEHeader(); err != nil {
+						return
+					}
+					lock.Lock()
+					commands = append(commands, line)
+					lock.Unlock()
+					_, _ = c.Write([]byte("Content-Type: command/reply\nReply-Text: +OK\n\n"))
+					if strings.HasPrefix(line, "event ") {
+						_, _ = c.Write([]byte(event))
+						// Simulate FreeSWITCH restarting
+						time.Sleep(50 * time.Millisecond)
+						return
+					}
+				}
+			}(c, events[min(connection, len(events)-1)])
+		}
+	}()
+	return listener.Addr().String(), func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), commands...)
+	}
+}
+
+func TestBridge_Reconnect(t *testing.T) {
+	address, commands := fakeFreeSWITCH(t,
+		testEvent(`{"Event-Name":"CHANNEL_CREATE","Unique-ID":"first","Caller-Caller-ID-Name":"Front Desk","variable_sip_call_id":"abc"}`),
+		testEvent(`{"Event-Name":"CHANNEL_HANGUP_COMPLETE","Unique-ID":"second"}`),
+	)
+	cfg, err := Config{
+		Address:          address,
+		Events:           []string{"CHANNEL_CREATE", "CHANNEL_HANGUP_COMPLETE"},
+		Filters:          []FilterConfig{{Header: "Caller-Context", Value: "default"}},
+		LivenessInterval: Duration(20 * time.Millisecond),
+		Reconnect:        BackoffConfig{Min: Duration(10 * time.Millisecond)},
+		Sinks: []SinkConfig{
+			{Name: "all", Type: "stdout", BatchInterval: Duration(10 * time.Millisecond)},
+			{Name: "hangups", Type: "stdout", BatchInterval: Duration(10 * time.Millisecond), Events: []string{"CHANNEL_HANGUP_COMPLETE"}},
+		},
+	}.withDefaults()
+	assert.Nil(t, err)
+
+	all, hangups := &testSink{}, &testSink{}
+	b, err := newBridge(cfg, []Sink{all, hangups}, testLogger())
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		b.run(ctx)
+		close(stopped)
+	}()
+
+	// The first connection is closed after the first event, the second event is received after reconnecting
+	assert.Eventually(t, func() bool {
+		return len(hangups.received()) == 1
+	}, 5*time.Second, time.Millisecond)
+	cancel()
+	<-stopped
+
+	var events []map[string]string
+	for _, batch := range all.received() {
+		for _, event := range batch {
+			var decoded map[string]string
+			assert.Nil(t, json.Unmarshal([]byte(event), &decoded))
+			events = append(events, decoded)
+		}
+	}
+	if assert.GreaterOrEqual(t, len(events), 2) {
+		assert.Equal(t, "first", events[0]["Unique-ID"])
+		assert.Equal(t, "Front Desk", events[0]["Caller-Caller-ID-Name"])
+		assert.Equal(t, "abc", events[0]["variable_sip_call_id"])
+		assert.Equal(t, "CHANNEL_HANGUP_COMPLETE", events[1]["Event-Name"])
+	}
+	assert.Equal(t, [][]string{{`{"Event-Name":"CHANNEL_HANGUP_COMPLETE","Unique-ID":"second"}`}}, hangups.received())
+	assert.Equal(t, []string{"auth ClueCon", "filter Caller-Context default", "event json CHANNEL_CREATE CHANNEL_HANGUP_COMPLETE"}, commands()[:3])
+}
+
+func TestBridge_ForwardSubscribed(t *testing.T) {
+	cfg, err := Config{
+		Events:   []string{"channel_create"},
+		Custom:   []string{"sofia::register"},
+		Liveness: "heartbeat",
+		Sinks:    []SinkConfig{{Type: "stdout"}},
+	}.withDefaults()
+	assert.Nil(t, err)
+	b, err := newBridge(cfg, []Sink{&testSink{}}, testLogger())
+	assert.Nil(t, err)
+
+	for _, event := range [][2]string{
+		{"CHANNEL_CREATE", ""},
+		{"HEARTBEAT", ""},
+		{"CUSTOM", "sofia::register"},
+		{"CUSTOM", "sofia::unregister"},
+	} {
+		data, _ := json.Marshal(map[string]string{"Event-Name": event[0], "Event-Subclass": event[1]})
+		b.forward(&eslgo.Event{
+			Headers: textproto.MIMEHeader{"Event-Name": {event[0]}, "Event-Subclass": {event[1]}},
+			JSON:    data,
+		})
+	}
+	var forwarded []string
+	for len(b.forwarders[0].queue) > 0 {
+		forwarded = append(forwarded, string((<-b.forwarders[0].queue).data))
+	}
+	assert.Equal(t, []string{
+		`{"Event-Name":"CHANNEL_CREATE","Event-Subclass":""}`,
+		`{"Event-Name":"CUSTOM","Event-Subclass":"sofia::register"}`,
+	}, forwarded)
+}
@@ -0,0 +1,183 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Config - The JSON configuration file of the daemon
+type Config struct {
+	Address          string         `json:"address"`           // FreeSWITCH event socket address, defaults to 127.0.0.1:8021
+	Password         string         `json:"password"`          // Defaults to ClueCon
+	Events           []string       `json:"events"`            // Event names to subscribe to, defaults to ALL
+	Custom           []string       `json:"custom"`            // CUSTOM event subclasses to subscribe to e.g. sofia::register
+	Filters          []FilterConfig `json:"filters"`           // FreeSWITCH side event filters, only matching events are sent to us
+	Liveness         string         `json:"liveness"`          // How dead connections are detected, probe(api status), heartbeat(HEARTBEAT events) or off. Defaults to probe
+	LivenessInterval Duration       `json:"liveness_interval"` // How often probes are sent or HEARTBEAT events are expected, defaults to 10s for probe and 20s for heartbeat
+	Reconnect        BackoffConfig  `json:"reconnect"`         // Delay between connection attempts, defaults to 1s doubling up to 30s
+	SpoolDir         string         `json:"spool_dir"`         // Where batches are stored while a sink is down, spooling is disabled when empty
+	Sinks            []SinkConfig   `json:"sinks"`
+}
+
+// FilterConfig - An event filter, see the filter command of mod_event_socket
+type FilterConfig struct {
+	Header string `json:"header"`
+	Value  string `json:"value"`
+}
+
+// BackoffConfig - An exponential backoff
+type BackoffConfig struct {
+	Min Duration `json:"min"`
+	Max Duration `json:"max"`
+}
+
+// SinkConfig - Where events are forwarded to
+type SinkConfig struct {
+	Name          string            `json:"name"`           // Used for logging and the spool directory, defaults to the type and index
+	Type          string            `json:"type"`           // http, file or stdout
+	URL           string            `json:"url"`            // The webhook of http sinks, batches are POSTed as a JSON array
+	Headers       map[string]string `json:"headers"`        // Extra HTTP headers e.g. Authorization
+	Path          string            `json:"path"`           // The NDJSON file of file sinks, appended to
+	Events        []string          `json:"events"`         // Only forward these events to the sink, all subscribed events when empty
+	BatchSize     int               `json:"batch_size"`     // Events sent at once, defaults to 100
+	BatchInterval Duration          `json:"batch_interval"` // How long to wait for a batch to fill up, defaults to 1s
+	Timeout       Duration          `json:"timeout"`        // Timeout of each send, defaults to 10s
+	Retries       int               `json:"retries"`        // Attempts after the first failed send before the batch is spooled, defaults to 5
+	Backoff       BackoffConfig     `json:"backoff"`        // Delay between retries, defaults to 500ms doubling up to 30s
+}
+
+// Duration - A time.Duration read from a JSON string such as "1m30s"
+type Duration time.Duration
+
+// UnmarshalJSON - Implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("durations must be strings such as \"5s\": %w", err)
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// LoadConfig - Reads the configuration file and applies the defaults
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	var cfg Config
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg.withDefaults()
+}
+
+func (c Config) withDefaults() (Config, error) {
+	if len(c.Address) == 0 {
+		c.Address = "127.0.0.1:8021"
+	}
+	if len(c.Password) == 0 {
+		c.Password = "ClueCon"
+	}
+	if len(c.Events) == 0 && len(c.Custom) == 0 {
+		c.Events = []string{"ALL"}
+	}
+	switch c.Liveness {
+	case "":
+		c.Liveness = "probe"
+	case "probe", "heartbeat", "off":
+	default:
+		return c, fmt.Errorf("unknown liveness %q", c.Liveness)
+	}
+	if c.LivenessInterval <= 0 && c.Liveness == "probe" {
+		c.LivenessInterval = Duration(10 * time.Second)
+	}
+	c.Reconnect = c.Reconnect.withDefaults(time.Second, 30*time.Second)
+	if len(c.Sinks) == 0 {
+		return c, errors.New("no sinks configured")
+	}
+	names := make(map[string]bool)
+	for i, sink := range c.Sinks {
+		if len(sink.Name) == 0 {
+			sink.Name = fmt.Sprintf("%s-%d", sink.Type, i)
+		}
+		if names[sink.Name] {
+			return c, fmt.Errorf("duplicate sink name %s", sink.Name)
+		}
+		names[sink.Name] = true
+		switch sink.Type {
+		case "http":
+			if len(sink.URL) == 0 {
+				return c, fmt.Errorf("sink %s: url is required", sink.Name)
+			}
+		case "file":
+			if len(sink.Path) == 0 {
+				return c, fmt.Errorf("sink %s: path is required", sink.Name)
+			}
+		case "stdout":
+		default:
+			return c, fmt.Errorf("sink %s: unknown type %q", sink.Name, sink.Type)
+		}
+		if sink.BatchSize <= 0 {
+			sink.BatchSize = 100
+		}
+		if sink.BatchInterval <= 0 {
+			sink.BatchInterval = Duration(time.Second)
+		}
+		if sink.Timeout <= 0 {
+			sink.Timeout = Duration(10 * time.Second)
+		}
+		if sink.Retries <= 0 {
+			sink.Retries = 5
+		}
+		sink.Backoff = sink.Backoff.withDefaults(500*time.Millisecond, 30*time.Second)
+		for j, name := range sink.Events {
+			sink.Events[j] = strings.ToUpper(name)
+		}
+		c.Sinks[i] = sink
+	}
+	return c, nil
+}
+
+func (b BackoffConfig) withDefaults(min, max time.Duration) BackoffConfig {
+	if b.Min <= 0 {
+		b.Min = Duration(min)
+	}
+	if b.Max < b.Min {
+		b.Max = Duration(max)
+		if b.Max < b.Min {
+			b.Max = b.Min
+		}
+	}
+	return b
+}
+
+// delay - The delay before the attempt, starting at 0 for the first retry
+func (b BackoffConfig) delay(attempt int) time.Duration {
+	delay := time.Duration(b.Min)
+	for i := 0; i < attempt && delay < time.Duration(b.Max); i++ {
+		delay *= 2
+	}
+	if delay > time.Duration(b.Max) {
+		delay = time.Duration(b.Max)
+	}
+	return delay
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "eslbridge.json")
+	assert.Nil(t, os.WriteFile(path, []byte(`{
+		"events": ["CHANNEL_HANGUP_COMPLETE"],
+		"reconnect": {"min": "2s"},
+		"sinks": [
+			{"type": "http", "url": "http://localhost/events", "events": ["channel_hangup_complete"], "batch_interval": "250ms"},
+			{"type": "stdout"}
+		]
+	}`), 0644))
+
+	cfg, err := LoadConfig(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:8021", cfg.Address)
+	assert.Equal(t, "ClueCon", cfg.Password)
+	assert.Equal(t, "probe", cfg.Liveness)
+	assert.Equal(t, Duration(2*time.Second), cfg.Reconnect.Min)
+	assert.Equal(t, Duration(30*time.Second), cfg.Reconnect.Max)
+	assert.Equal(t, "http-0", cfg.Sinks[0].Name)
+	assert.Equal(t, []string{"CHANNEL_HANGUP_COMPLETE"}, cfg.Sinks[0].Events)
+	assert.Equal(t, Duration(250*time.Millisecond), cfg.Sinks[0].BatchInterval)
+	assert.Equal(t, 100, cfg.Sinks[1].BatchSize)
+	assert.Equal(t, 5, cfg.Sinks[1].Retries)
+
+	for _, invalid := range []string{
+		`{}`,
+		`{"sinks": [{"type": "kafka"}]}`,
+		`{"sinks": [{"type": "http"}]}`,
+		`{"sinks": [{"type": "file"}]}`,
+		`{"sinks": [{"name": "a", "type": "stdout"}, {"name": "a", "type": "stdout"}]}`,
+		`{"reconnect": {"min": 5}, "sinks": [{"type": "stdout"}]}`,
+	} {
+		assert.Nil(t, os.WriteFile(path, []byte(invalid), 0644))
+		_, err = LoadConfig(path)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBackoffConfig_Delay(t *testing.T) {
+	backoff := BackoffConfig{Min: Duration(time.Second), Max: Duration(5 * time.Second)}
+	assert.Equal(t, time.Second, backoff.delay(0))
+	assert.Equal(t, 2*time.Second, backoff.delay(1))
+	assert.Equal(t, 4*time.Second, backoff.delay(2))
+	assert.Equal(t, 5*time.Second, backoff.delay(3))
+	assert.Equal(t, 5*time.Second, backoff.delay(100))
+}
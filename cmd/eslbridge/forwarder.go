@@ -0,0 +1,308 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// queueSize - Events waiting for a sink before they are spooled, or dropped without a spool
+const queueSize = 10000
+
+// queuedEvent - An event with the time it was queued at. Spool files are named after the stamp of their first event so the spool
+// keeps the order the events were received in, whichever goroutine writes them
+type queuedEvent struct {
+	stamp int64
+	data  json.RawMessage
+}
+
+// forwarder - Batches events for a sink, retrying failed batches with backoff and spooling them once the retries run out
+type forwarder struct {
+	cfg    SinkConfig
+	sink   Sink
+	spool  *spool // nil when spooling is disabled
+	events map[string]bool
+	queue  chan queuedEvent
+	log    *slog.Logger
+	done   chan struct{}
+
+	// Used by enqueue and run
+	overflowLock sync.Mutex
+	stamp        int64         // The stamp of the last queued event
+	overflow     []queuedEvent // Events that did not fit in the queue waiting to be spooled, always newer than the queued events
+
+	// Only used by the run goroutine
+	dequeued      int64 // The stamp of the last event taken from the queue
+	drainFailures int
+	nextDrain     time.Time
+}
+
+func newForwarder(cfg SinkConfig, sink Sink, spoolDir string, logger *slog.Logger) (*forwarder, error) {
+	f := &forwarder{
+		cfg:    cfg,
+		sink:   sink,
+		events: make(map[string]bool),
+		queue:  make(chan queuedEvent, queueSize),
+		log:    logger.With("sink", cfg.Name),
+		done:   make(chan struct{}),
+	}
+	for _, name := range cfg.Events {
+		f.events[name] = true
+	}
+	if len(spoolDir) > 0 {
+		var err error
+		if f.spool, err = newSpool(filepath.Join(spoolDir, cfg.Name)); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// wants - Returns true if the sink forwards events with the name or CUSTOM subclass
+func (f *forwarder) wants(name, subclass string) bool {
+	return len(f.events) == 0 || f.events[name] || (len(subclass) > 0 && f.events[subclass])
+}
+
+// enqueue - Queues the event without ever blocking, it is called from the event loop of the connection. When the queue is full the
+// event is dropped without a spool, with a spool it is written to the spool in batches instead
+func (f *forwarder) enqueue(event json.RawMessage) {
+	f.overflowLock.Lock()
+	defer f.overflowLock.Unlock()
+
+	// Stamps must be unique and increasing even if the clock is not
+	f.stamp = max(time.Now().UnixNano(), f.stamp+1)
+	queued := queuedEvent{stamp: f.stamp, data: event}
+	if f.spool == nil {
+		select {
+		case f.queue <- queued:
+		default:
+			f.log.Warn("Queue full, dropping event")
+		}
+		return
+	}
+
+	select {
+	case <-f.done:
+		// Nothing reads the queue any more
+		f.overflow = append(f.overflow, queued)
+		f.spoolOverflow()
+		return
+	default:
+	}
+	if len(f.overflow) > 0 && len(f.queue) < cap(f.queue) {
+		// The overflow is older than the event, it has to be in the spool before the event can be queued
+		f.spoolOverflow()
+	}
+	if len(f.overflow) == 0 {
+		select {
+		case f.queue <- queued:
+			return
+		default:
+			f.log.Debug("Queue full, spooling event")
+		}
+	}
+	f.overflow = append(f.overflow, queued)
+	if len(f.overflow) >= f.cfg.BatchSize {
+		f.spoolOverflow()
+	}
+}
+
+// spoolOverflow - Spools the events that did not fit in the queue. Must be called with the overflow lock held
+func (f *forwarder) spoolOverflow() {
+	f.store(f.overflow)
+	f.overflow = nil
+}
+
+// run - Sends batches until the context is done, the pending batch is then sent or spooled once more before returning
+func (f *forwarder) run(ctx context.Context) {
+	defer close(f.done)
+	ticker := time.NewTicker(time.Duration(f.cfg.BatchInterval))
+	defer ticker.Stop()
+
+	var batch []queuedEvent
+	for {
+		select {
+		case event := <-f.queue:
+			f.dequeued = event.stamp
+			batch = append(batch, event)
+			if len(batch) < f.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				f.overflowLock.Lock()
+				if len(f.overflow) > 0 {
+					f.spoolOverflow()
+				}
+				f.overflowLock.Unlock()
+				if len(f.queue) == 0 {
+					// Everything spooled is older than what will be queued next
+					f.drainSpool(ctx, math.MaxInt64)
+				} else {
+					// Events still queued must not be overtaken by the newer overflow
+					f.drainSpool(ctx, f.dequeued+1)
+				}
+				continue
+			}
+		case <-ctx.Done():
+			// Take what is still queued and make one last attempt without retries, spooling what fails
+			for len(f.queue) > 0 {
+				batch = append(batch, <-f.queue)
+			}
+			f.flush(context.Background(), batch, 0, false)
+			f.overflowLock.Lock()
+			if len(f.overflow) > 0 {
+				f.spoolOverflow()
+			}
+			f.overflowLock.Unlock()
+			return
+		}
+		f.flush(ctx, batch, f.cfg.Retries, true)
+		batch = nil
+	}
+}
+
+// flush - Sends the batch in chunks of the batch size, spooling what could not be sent. Events spooled before a chunk are sent
+// first, when drain is false or they cannot be sent the chunk is spooled behind them to keep the order
+func (f *forwarder) flush(ctx context.Context, batch []queuedEvent, retries int, drain bool) {
+	for start := 0; start < len(batch); start += f.cfg.BatchSize {
+		chunk := batch[start:min(start+f.cfg.BatchSize, len(batch))]
+		if !f.spooledBefore(ctx, chunk[0].stamp, drain) {
+			f.store(batch[start:])
+			return
+		}
+		if err := f.send(ctx, eventData(chunk), retries); err != nil {
+			f.log.Warn("Failed to send batch", "events", len(chunk), "error", err)
+			// The sink is down, do not wait for the rest to fail as well
+			f.store(batch[start:])
+			return
+		}
+	}
+}
+
+// spooledBefore - Returns true if nothing older than the stamp is spooled, draining those events first when drain is true
+func (f *forwarder) spooledBefore(ctx context.Context, stamp int64, drain bool) bool {
+	if f.spool == nil {
+		return true
+	}
+	if drain {
+		return f.drainSpool(ctx, stamp)
+	}
+	names, err := f.spool.files()
+	return err == nil && (len(names) == 0 || spoolStamp(names[0]) >= stamp)
+}
+
+// send - Sends the batch, retrying with backoff
+func (f *forwarder) send(ctx context.Context, batch []json.RawMessage, retries int) error {
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			timer := time.NewTimer(f.cfg.Backoff.delay(attempt - 1))
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			}
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, time.Duration(f.cfg.Timeout))
+		err = f.sink.Send(sendCtx, batch)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		f.log.Debug("Send failed", "attempt", attempt+1, "error", err)
+	}
+	return err
+}
+
+// store - Spools the batch in files of at most the batch size, the events are lost without a spool
+func (f *forwarder) store(batch []queuedEvent) {
+	if f.spool == nil {
+		f.log.Error("Dropping batch, no spool configured", "events", len(batch))
+		return
+	}
+	for len(batch) > 0 {
+		size := min(len(batch), f.cfg.BatchSize)
+		if err := f.spool.write(batch[0].stamp, eventData(batch[:size])); err != nil {
+			f.log.Error("Dropping batch, failed to spool it", "events", size, "error", err)
+		}
+		batch = batch[size:]
+	}
+}
+
+// drainSpool - Sends the spooled batches older than the stamp oldest first, backing off after a failure so a down sink is not hammered.
+// Returns true once nothing older than the stamp is spooled
+func (f *forwarder) drainSpool(ctx context.Context, before int64) bool {
+	if f.spool == nil {
+		return true
+	}
+	names, err := f.spool.files()
+	if err != nil {
+		f.log.Error("Failed to list spool", "error", err)
+		return false
+	}
+	if len(names) == 0 || spoolStamp(names[0]) >= before {
+		return true
+	}
+	if time.Now().Before(f.nextDrain) {
+		return false
+	}
+	for _, name := range names {
+		if spoolStamp(name) >= before {
+			break
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		batch, err := f.spool.read(name)
+		if err != nil {
+			// Set the file aside, leaving it would block the spool forever
+			f.log.Error("Failed to read spooled batch, setting it aside", "file", name, "error", err)
+			if err = f.spool.reject(name); err != nil {
+				f.log.Error("Failed to set spooled batch aside", "file", name, "error", err)
+				return false
+			}
+			continue
+		}
+		if err = f.send(ctx, batch, 0); err != nil {
+			f.nextDrain = time.Now().Add(f.cfg.Backoff.delay(f.drainFailures))
+			f.drainFailures++
+			return false
+		}
+		f.drainFailures = 0
+		if err = f.spool.remove(name); err != nil {
+			f.log.Error("Failed to remove spooled batch", "file", name, "error", err)
+			return false
+		}
+	}
+	return true
+}
+
+// wait - Waits for run to return
+func (f *forwarder) wait() {
+	<-f.done
+}
+
+// eventData - Returns the events without their stamps
+func eventData(events []queuedEvent) []json.RawMessage {
+	data := make([]json.RawMessage, len(events))
+	for i, event := range events {
+		data[i] = event.data
+	}
+	return data
+}
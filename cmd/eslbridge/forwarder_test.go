@@ -0,0 +1,252 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"log/slog"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testSink - Records batches, failing while down is set
+type testSink struct {
+	lock    sync.Mutex
+	down    bool
+	batches [][]string
+	calls   int
+}
+
+func (s *testSink) Send(ctx context.Context, batch []json.RawMessage) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls++
+	if s.down {
+		return errors.New("sink down")
+	}
+	var events []string
+	for _, event := range batch {
+		events = append(events, string(event))
+	}
+	s.batches = append(s.batches, events)
+	return nil
+}
+
+func (s *testSink) Close() error {
+	return nil
+}
+
+func (s *testSink) setDown(down bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.down = down
+}
+
+func (s *testSink) received() [][]string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([][]string(nil), s.batches...)
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testSinkConfig() SinkConfig {
+	return SinkConfig{
+		Name:          "test",
+		BatchSize:     2,
+		BatchInterval: Duration(10 * time.Millisecond),
+		Timeout:       Duration(time.Second),
+		Retries:       2,
+		Backoff:       BackoffConfig{Min: Duration(time.Millisecond), Max: Duration(5 * time.Millisecond)},
+	}
+}
+
+func TestForwarder_Batching(t *testing.T) {
+	sink := &testSink{}
+	f, err := newForwarder(testSinkConfig(), sink, "", testLogger())
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	go f.run(ctx)
+
+	for _, event := range []string{`1`, `2`, `3`} {
+		f.enqueue(json.RawMessage(event))
+	}
+	// The first two are sent as soon as the batch is full, the last one once the interval passes
+	assert.Eventually(t, func() bool {
+		return len(sink.received()) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, [][]string{{"1", "2"}, {"3"}}, sink.received())
+
+	cancel()
+	f.wait()
+}
+
+func TestForwarder_Retry(t *testing.T) {
+	sink := &testSink{down: true}
+	cfg := testSinkConfig()
+	cfg.Retries = 10
+	f, err := newForwarder(cfg, sink, "", testLogger())
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.run(ctx)
+
+	f.enqueue(json.RawMessage(`1`))
+	f.enqueue(json.RawMessage(`2`))
+	assert.Eventually(t, func() bool {
+		sink.lock.Lock()
+		defer sink.lock.Unlock()
+		return sink.calls >= 2
+	}, time.Second, time.Millisecond)
+	sink.setDown(false)
+	assert.Eventually(t, func() bool {
+		return len(sink.received()) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, [][]string{{"1", "2"}}, sink.received())
+}
+
+func TestForwarder_Spool(t *testing.T) {
+	sink := &testSink{down: true}
+	dir := t.TempDir()
+	f, err := newForwarder(testSinkConfig(), sink, dir, testLogger())
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	go f.run(ctx)
+
+	// Retries run out while the sink is down so both batches end up in the spool
+	for _, event := range []string{`1`, `2`, `3`, `4`} {
+		f.enqueue(json.RawMessage(event))
+	}
+	assert.Eventually(t, func() bool {
+		names, _ := f.spool.files()
+		return len(names) == 2
+	}, time.Second, time.Millisecond)
+	assert.Empty(t, sink.received())
+
+	// Once the sink is back the spool is drained oldest first
+	sink.setDown(false)
+	assert.Eventually(t, func() bool {
+		names, _ := f.spool.files()
+		return len(names) == 0
+	}, 5*time.Second, time.Millisecond)
+	assert.Equal(t, [][]string{{"1", "2"}, {"3", "4"}}, sink.received())
+
+	// Pending events are spooled when stopping while the sink is down
+	sink.setDown(true)
+	f.enqueue(json.RawMessage(`5`))
+	cancel()
+	f.wait()
+	names, err := f.spool.files()
+	assert.Nil(t, err)
+	if assert.Len(t, names, 1) {
+		batch, err := f.spool.read(names[0])
+		assert.Nil(t, err)
+		assert.Equal(t, []json.RawMessage{json.RawMessage(`5`)}, batch)
+	}
+}
+
+func TestForwarder_SpoolOrder(t *testing.T) {
+	sink := &testSink{down: true}
+	f, err := newForwarder(testSinkConfig(), sink, t.TempDir(), testLogger())
+	assert.Nil(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	go f.run(ctx)
+
+	f.enqueue(json.RawMessage(`1`))
+	f.enqueue(json.RawMessage(`2`))
+	assert.Eventually(t, func() bool {
+		names, _ := f.spool.files()
+		return len(names) == 1
+	}, time.Second, time.Millisecond)
+
+	// Events received after the outage must not overtake the spooled ones
+	sink.setDown(false)
+	for _, event := range []string{`3`, `4`, `5`} {
+		f.enqueue(json.RawMessage(event))
+	}
+	assert.Eventually(t, func() bool {
+		names, _ := f.spool.files()
+		return len(names) == 0 && len(sink.received()) == 3
+	}, 5*time.Second, time.Millisecond)
+	var events []string
+	for _, batch := range sink.received() {
+		events = append(events, batch...)
+	}
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, events)
+
+	cancel()
+	f.wait()
+}
+
+// blockingSink - A testSink that does not return from Send until released
+type blockingSink struct {
+	testSink
+	release chan struct{}
+}
+
+func (s *blockingSink) Send(ctx context.Context, batch []json.RawMessage) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return s.testSink.Send(ctx, batch)
+}
+
+func TestForwarder_Overflow(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	cfg := testSinkConfig()
+	cfg.Timeout = Duration(10 * time.Second)
+	f, err := newForwarder(cfg, sink, t.TempDir(), testLogger())
+	assert.Nil(t, err)
+	f.queue = make(chan queuedEvent, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	go f.run(ctx)
+
+	// A slow sink must not block the caller, the events that do not fit in the queue are spooled instead
+	var want []string
+	for i := 1; i <= 10; i++ {
+		event := strconv.Itoa(i)
+		want = append(want, event)
+		f.enqueue(json.RawMessage(event))
+		if i == 2 {
+			// Wait for the first batch to be taken so the sink is blocked sending it
+			assert.Eventually(t, func() bool {
+				return len(f.queue) == 0
+			}, time.Second, time.Millisecond)
+		}
+	}
+	names, err := f.spool.files()
+	assert.Nil(t, err)
+	assert.Len(t, names, 3)
+
+	close(sink.release)
+	assert.Eventually(t, func() bool {
+		names, _ := f.spool.files()
+		return len(names) == 0 && len(sink.received()) == 5
+	}, 5*time.Second, time.Millisecond)
+	var events []string
+	for _, batch := range sink.received() {
+		events = append(events, batch...)
+	}
+	assert.Equal(t, want, events)
+
+	cancel()
+	f.wait()
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+
+// eslbridge forwards FreeSWITCH events as JSON to webhooks, NDJSON files or stdout.
+//
+//	eslbridge -config /etc/eslbridge.json
+//
+// An example configuration forwarding hangups to a webhook and everything to a file:
+//
+//	{
+//	  "address": "127.0.0.1:8021",
+//	  "password": "ClueCon",
+//	  "events": ["CHANNEL_CREATE", "CHANNEL_HANGUP_COMPLETE"],
+//	  "custom": ["sofia::register"],
+//	  "filters": [{"header": "Caller-Context", "value": "default"}],
+//	  "spool_dir": "/var/spool/eslbridge",
+//	  "sinks": [
+//	    {"name": "billing", "type": "http", "url": "https://billing.example.com/events", "events": ["CHANNEL_HANGUP_COMPLETE"],
+//	     "headers": {"Authorization": "Bearer secret"}, "batch_size": 50, "batch_interval": "2s"},
+//	    {"name": "archive", "type": "file", "path": "/var/log/freeswitch/events.ndjson"}
+//	  ]
+//	}
+//
+// Batches that cannot be sent after the retries are spooled to disk and sent once the sink is back, oldest first.
+// The connection is monitored and re-established with backoff when it is lost.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	configPath := flag.String("config", "/etc/eslbridge.json", "Path to the JSON configuration file")
+	debug := flag.Bool("debug", false, "Log debug messages")
+	flag.Parse()
+
+	level := slog.LevelInfo
+	if *debug {
+		level = slog.LevelDebug
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	cfg, err := LoadConfig(*configPath)
+	if err != nil {
+		logger.Error("Failed to load config", "error", err)
+		os.Exit(1)
+	}
+
+	var sinks []Sink
+	for _, sinkConfig := range cfg.Sinks {
+		sink, err := NewSink(sinkConfig)
+		if err != nil {
+			logger.Error("Failed to create sink", "sink", sinkConfig.Name, "error", err)
+			os.Exit(1)
+		}
+		defer sink.Close()
+		sinks = append(sinks, sink)
+	}
+	b, err := newBridge(cfg, sinks, logger)
+	if err != nil {
+		logger.Error("Failed to set up", "error", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	b.run(ctx)
+	logger.Info("Stopped")
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Sink - Somewhere events are forwarded to. Send is called with batches of JSON encoded events from a single goroutine,
+// an error makes the batch be retried and eventually spooled so it must not be partially applied where possible
+type Sink interface {
+	Send(ctx context.Context, batch []json.RawMessage) error
+	Close() error
+}
+
+// HTTPSink - POSTs batches to a webhook as a JSON array. Any status other than 2xx is an error
+type HTTPSink struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+// WriterSink - Writes events as NDJSON, one JSON object per line
+type WriterSink struct {
+	lock   sync.Mutex
+	writer io.Writer
+	closer io.Closer
+}
+
+// NewSink - Creates the sink from its configuration
+func NewSink(cfg SinkConfig) (Sink, error) {
+	switch cfg.Type {
+	case "http":
+		return &HTTPSink{URL: cfg.URL, Headers: cfg.Headers, Client: http.DefaultClient}, nil
+	case "file":
+		file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return &WriterSink{writer: file, closer: file}, nil
+	case "stdout":
+		return NewWriterSink(os.Stdout), nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q", cfg.Type)
+}
+
+// NewWriterSink - Creates a sink writing NDJSON to the writer, the writer is not closed by the sink
+func NewWriterSink(writer io.Writer) *WriterSink {
+	return &WriterSink{writer: writer}
+}
+
+// Send - Implements Sink
+func (s *HTTPSink) Send(ctx context.Context, batch []json.RawMessage) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for name, value := range s.Headers {
+		request.Header.Set(name, value)
+	}
+	response, err := s.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook replied %s", response.Status)
+	}
+	return nil
+}
+
+// Close - Implements Sink
+func (s *HTTPSink) Close() error {
+	return nil
+}
+
+// Send - Implements Sink, the batch is written with a single write so a failed write does not leave half a batch behind
+func (s *WriterSink) Send(ctx context.Context, batch []json.RawMessage) error {
+	var buffer bytes.Buffer
+	for _, event := range batch {
+		buffer.Write(event)
+		buffer.WriteByte('\n')
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, err := s.writer.Write(buffer.Bytes())
+	return err
+}
+
+// Close - Implements Sink
+func (s *WriterSink) Close() error {
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
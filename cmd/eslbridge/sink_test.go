@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHTTPSink(t *testing.T) {
+	var received []map[string]string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	sink, err := NewSink(SinkConfig{Type: "http", URL: server.URL, Headers: map[string]string{"Authorization": "Bearer secret"}})
+	assert.Nil(t, err)
+	defer sink.Close()
+	batch := []json.RawMessage{json.RawMessage(`{"Event-Name":"CHANNEL_CREATE"}`), json.RawMessage(`{"Event-Name":"CHANNEL_HANGUP"}`)}
+	assert.Nil(t, sink.Send(context.Background(), batch))
+	assert.Equal(t, []map[string]string{{"Event-Name": "CHANNEL_CREATE"}, {"Event-Name": "CHANNEL_HANGUP"}}, received)
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, sink.Send(context.Background(), batch))
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	sink, err := NewSink(SinkConfig{Type: "file", Path: path})
+	assert.Nil(t, err)
+	assert.Nil(t, sink.Send(context.Background(), []json.RawMessage{json.RawMessage(`{"a":"1"}`), json.RawMessage(`{"b":"2"}`)}))
+	assert.Nil(t, sink.Send(context.Background(), []json.RawMessage{json.RawMessage(`{"c":"3"}`)}))
+	assert.Nil(t, sink.Close())
+
+	data, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "{\"a\":\"1\"}\n{\"b\":\"2\"}\n{\"c\":\"3\"}\n", string(data))
+}
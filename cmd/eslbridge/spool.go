@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// spool - Stores batches a sink could not take as NDJSON files, one file per batch, so they survive restarts
+type spool struct {
+	dir      string
+	sequence atomic.Uint64
+}
+
+func newSpool(dir string) (*spool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &spool{dir: dir}, nil
+}
+
+// write - Stores the batch under the stamp of its first event, files are replayed in stamp order. The file is written under a temporary
+// name first so a crash never leaves half a batch to replay
+func (s *spool) write(stamp int64, batch []json.RawMessage) error {
+	var buffer bytes.Buffer
+	for _, event := range batch {
+		buffer.Write(event)
+		buffer.WriteByte('\n')
+	}
+	name := fmt.Sprintf("%020d-%06d.ndjson", stamp, s.sequence.Add(1)%1000000)
+	temporary := filepath.Join(s.dir, "."+name+".tmp")
+	if err := os.WriteFile(temporary, buffer.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, filepath.Join(s.dir, name))
+}
+
+// files - Returns the spooled batches, oldest first
+func (s *spool) files() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") && strings.HasSuffix(entry.Name(), ".ndjson") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// read - Reads a spooled batch
+func (s *spool) read(name string) ([]json.RawMessage, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	var batch []json.RawMessage
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			batch = append(batch, json.RawMessage(append([]byte(nil), line...)))
+		}
+	}
+	return batch, scanner.Err()
+}
+
+func (s *spool) remove(name string) error {
+	return os.Remove(filepath.Join(s.dir, name))
+}
+
+// reject - Renames a batch that cannot be read so it is kept for inspection but no longer replayed
+func (s *spool) reject(name string) error {
+	path := filepath.Join(s.dir, name)
+	return os.Rename(path, path+".rejected")
+}
+
+// spoolStamp - Returns the stamp of the first event of a spooled batch
+func spoolStamp(name string) int64 {
+	stamp, _ := strconv.ParseInt(strings.SplitN(name, "-", 2)[0], 10, 64)
+	return stamp
+}
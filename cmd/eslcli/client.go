@@ -0,0 +1,385 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/percipia/eslgo"
+	"github.com/percipia/eslgo/command"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const helpText = `Commands are sent as api commands, or as bgapi commands when they start with bgapi. Client commands:
+/event [plain|json] [NAMES...]  Display events, all events when no names are given
+/noevents                       Stop displaying events
+/uuid [UUID]                    Only display events of the channel, all channels when no UUID is given
+/log [LEVEL]                    Display FreeSWITCH logs at the level, debug when no level is given
+/nolog                          Stop displaying logs
+/history                        Show the command history
+/help                           Show this help
+/exit, /quit, /bye              Exit`
+
+// client - Executes prompt lines on the connection and displays events and logs
+type client struct {
+	conn    *eslgo.Conn
+	console *console
+	timeout time.Duration
+
+	lock          sync.Mutex
+	json          bool
+	uuid          string
+	events        map[string]bool // Names of events to display, nil for none and empty for all
+	eventListener string
+	logListener   string
+	history       func() []string
+}
+
+func newClient(conn *eslgo.Conn, console *console, timeout time.Duration) *client {
+	return &client{
+		conn:    conn,
+		console: console,
+		timeout: timeout,
+	}
+}
+
+// start - Subscribes to BACKGROUND_JOB events so bgapi results can be displayed
+func (c *client) start() error {
+	c.eventListener = c.conn.RegisterEventListener(eslgo.EventListenAll, c.onEvent)
+	return c.send(command.Event{Format: "json", Listen: []string{"BACKGROUND_JOB"}})
+}
+
+// execute - Executes a prompt line. Returns true when the client should exit
+func (c *client) execute(line string) (bool, error) {
+	line = strings.TrimSpace(line)
+	if len(line) == 0 {
+		return false, nil
+	}
+	if !strings.HasPrefix(line, "/") {
+		return false, c.api(line)
+	}
+
+	fields := strings.Fields(line)
+	args := fields[1:]
+	switch strings.ToLower(fields[0]) {
+	case "/exit", "/quit", "/bye":
+		return true, nil
+	case "/help":
+		c.console.Println(helpText)
+	case "/event":
+		return false, c.enableEvents(args)
+	case "/noevents":
+		return false, c.disableEvents()
+	case "/uuid":
+		c.lock.Lock()
+		c.uuid = strings.Join(args, "")
+		c.lock.Unlock()
+	case "/log":
+		return false, c.enableLog(args)
+	case "/nolog":
+		return false, c.disableLog()
+	case "/history":
+		if c.history != nil {
+			for i, entry := range c.history() {
+				c.console.Printf("%4d  %s\n", i+1, entry)
+			}
+		}
+	default:
+		return false, fmt.Errorf("unknown command %s, type /help for a list", fields[0])
+	}
+	return false, nil
+}
+
+// api - Sends the line as an api or bgapi command and displays the result
+func (c *client) api(line string) error {
+	background := false
+	if name, rest, _ := strings.Cut(line, " "); strings.EqualFold(name, "bgapi") {
+		background = true
+		line = strings.TrimSpace(rest)
+	}
+	name, args, _ := strings.Cut(line, " ")
+	cmd := command.API{Command: name, Arguments: args, Background: background}
+
+	// The job is only started when bgapi is accepted, stopJob stops waiting for its result otherwise
+	stopJob := func() {}
+	if background {
+		cmd.JobUUID = uuid.New().String()
+		stopJob = c.displayJob(cmd.JobUUID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	response, err := c.conn.SendCommand(ctx, cmd)
+	if err != nil {
+		stopJob()
+		return err
+	}
+	if background {
+		c.console.Println(response.GetReply())
+	} else {
+		c.console.Println(strings.TrimRight(string(response.Body), "\n"))
+	}
+	if err = response.Err(cmd); err != nil {
+		stopJob()
+	}
+	return err
+}
+
+// displayJob - Displays the result of the background job once FreeSWITCH sends it. The returned function stops waiting for it
+func (c *client) displayJob(jobUUID string) func() {
+	events := make(chan *eslgo.Event, 1)
+	stop, done := make(chan struct{}), make(chan struct{})
+	id := c.conn.RegisterEventListener(jobUUID, func(event *eslgo.Event) {
+		if event.GetName() == "BACKGROUND_JOB" {
+			select {
+			case events <- event:
+			default:
+			}
+		}
+	})
+	go func() {
+		defer close(done)
+		defer c.conn.RemoveEventListener(jobUUID, id)
+		select {
+		case event := <-events:
+			c.console.Printf("Job %s finished:\n%s\n", jobUUID, strings.TrimRight(string(event.Body), "\n"))
+		case <-stop:
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+func (c *client) enableEvents(args []string) error {
+	format := ""
+	if len(args) > 0 && (args[0] == "plain" || args[0] == "json") {
+		format = args[0]
+		args = args[1:]
+	}
+	names := make(map[string]bool)
+	for _, name := range args {
+		names[strings.ToUpper(name)] = true
+	}
+	if names["ALL"] {
+		names = map[string]bool{}
+	}
+
+	listen := args
+	if len(names) == 0 {
+		listen = []string{"ALL"}
+	}
+	// Events are always received as json so they can be displayed exactly as FreeSWITCH sent them, the format only changes the display
+	if err := c.send(command.Event{Format: "json", Listen: listen}); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(format) > 0 {
+		c.json = format == "json"
+	}
+	c.events = names
+	return nil
+}
+
+func (c *client) disableEvents() error {
+	if err := c.send(command.DisableEvents{}); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.events = nil
+	c.lock.Unlock()
+	// noevents removes every subscription, bgapi results still need BACKGROUND_JOB
+	return c.send(command.Event{Format: "json", Listen: []string{"BACKGROUND_JOB"}})
+}
+
+func (c *client) enableLog(args []string) error {
+	level := eslgo.LogLevelDebug
+	if len(args) > 0 {
+		var err error
+		if level, err = parseLogLevel(args[0]); err != nil {
+			return err
+		}
+	}
+	if err := c.send(command.Log{Enabled: true, Level: level}); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if len(c.logListener) == 0 {
+		c.logListener = c.conn.RegisterLogListener(func(record *eslgo.LogRecord) {
+			c.console.Println(formatLogRecord(record))
+		})
+	}
+	return nil
+}
+
+func (c *client) disableLog() error {
+	c.lock.Lock()
+	if len(c.logListener) > 0 {
+		c.conn.RemoveLogListener(c.logListener)
+		c.logListener = ""
+	}
+	c.lock.Unlock()
+	return c.send(command.Log{})
+}
+
+// displaying - Returns true when events or logs are being displayed, the prompt then keeps running after the input ends
+func (c *client) displaying() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.displayingEvents() || len(c.logListener) > 0
+}
+
+// displayingEvents - Must be called with the lock held
+func (c *client) displayingEvents() bool {
+	return c.events != nil
+}
+
+func (c *client) onEvent(event *eslgo.Event) {
+	c.lock.Lock()
+	show := c.displayingEvents() && matchEvent(event, c.events, c.uuid)
+	asJSON := c.json
+	c.lock.Unlock()
+	if !show {
+		return
+	}
+	if asJSON {
+		c.console.Println(formatJSONEvent(event))
+	} else {
+		c.console.Println(formatPlainEvent(event))
+	}
+}
+
+func (c *client) send(cmd command.Command) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	_, err := c.conn.SendCommandChecked(ctx, cmd)
+	return err
+}
+
+// repl - Executes lines from the editor until the input ends, the user exits or FreeSWITCH disconnects. Returns the exit code
+func (c *client) repl(editor *lineEditor, disconnected <-chan struct{}) int {
+	c.history = editor.History
+	lines := make(chan string)
+	ready := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			line, err := editor.ReadLine()
+			if err != nil {
+				errs <- err
+				return
+			}
+			lines <- line
+			// Wait for the output of the line before prompting again
+			<-ready
+		}
+	}()
+
+	for {
+		select {
+		case line := <-lines:
+			quit, err := c.execute(line)
+			if err != nil {
+				c.console.Println("Error:", err)
+			}
+			if quit {
+				return 0
+			}
+			ready <- struct{}{}
+		case err := <-errs:
+			if errors.Is(err, io.EOF) && c.displaying() {
+				// Input ended but we were asked to display events, e.g. eslcli -events ALL < /dev/null
+				<-disconnected
+				return 1
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, errInterrupted) {
+				return 0
+			}
+			c.console.Println("Error:", err)
+			return 1
+		case <-disconnected:
+			c.console.Println("Disconnected")
+			return 1
+		}
+	}
+}
+
+// matchEvent - Returns true if the event has one of the names, or any name when names is empty, and belongs to the channel if set
+func matchEvent(event *eslgo.Event, names map[string]bool, channelUUID string) bool {
+	if len(names) > 0 && !names[event.GetName()] && !names[event.GetHeader("Event-Subclass")] {
+		return false
+	}
+	if len(channelUUID) > 0 && event.GetHeader("Unique-ID") != channelUUID && event.GetHeader("Other-Leg-Unique-ID") != channelUUID {
+		return false
+	}
+	return true
+}
+
+// formatPlainEvent - Formats the event with the headers sorted by name, followed by the body
+func formatPlainEvent(event *eslgo.Event) string {
+	var builder strings.Builder
+	for _, key := range sortedHeaders(event) {
+		builder.WriteString(key + ": " + event.GetHeader(key) + "\n")
+	}
+	if len(event.Body) > 0 {
+		builder.WriteString("\n")
+		builder.Write(event.Body)
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// formatJSONEvent - Formats the event as a single line JSON object exactly as FreeSWITCH sent it, the body is _body
+func formatJSONEvent(event *eslgo.Event) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, event.JSON); err != nil {
+		return string(event.JSON)
+	}
+	return compact.String()
+}
+
+func formatLogRecord(record *eslgo.LogRecord) string {
+	return fmt.Sprintf("[%s] %s:%d %s", record.LevelName(), record.File, record.Line, record.Text)
+}
+
+// parseLogLevel - Parses a level number or name such as debug or warning
+func parseLogLevel(level string) (int, error) {
+	if number, err := strconv.Atoi(level); err == nil && number >= eslgo.LogLevelConsole && number <= eslgo.LogLevelDebug {
+		return number, nil
+	}
+	for number := eslgo.LogLevelConsole; number <= eslgo.LogLevelDebug; number++ {
+		if strings.EqualFold((eslgo.LogRecord{Level: number}).LevelName(), level) {
+			return number, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %s", level)
+}
+
+func sortedHeaders(event *eslgo.Event) []string {
+	keys := make([]string, 0, len(event.Headers))
+	for key := range event.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// commands - Common API commands and client commands completed as the first word
+var commands = []string{
+	"bgapi", "conference", "console", "db", "echo", "eval", "fsctl", "global_getvar", "global_setvar", "hash", "help",
+	"hupall", "load", "module_exists", "originate", "reload", "reloadacl", "reloadxml", "show", "sofia", "status",
+	"unload", "uptime", "uuid_answer", "uuid_break", "uuid_bridge", "uuid_broadcast", "uuid_dump", "uuid_exists",
+	"uuid_getvar", "uuid_hold", "uuid_kill", "uuid_park", "uuid_record", "uuid_send_dtmf", "uuid_setvar",
+	"uuid_setvar_multi", "uuid_transfer", "version",
+	"/bye", "/event", "/exit", "/help", "/history", "/log", "/noevents", "/nolog", "/quit", "/uuid",
+}
+
+// subcommands - Words completed after the words of the key
+var subcommands = map[string][]string{
+	"show": {"aliases", "api", "application", "bridged_calls", "calls", "channels", "chat", "codec", "complete",
+		"detailed_bridged_calls", "detailed_calls", "dialplan", "endpoint", "file", "interfaces", "interface_types",
+		"management", "modules", "nat_map", "registrations", "say", "status", "tasks", "timer"},
+	"sofia":                {"global", "loglevel", "profile", "status", "tracelevel", "xmlstatus"},
+	"sofia status":         {"gateway", "profile"},
+	"sofia xmlstatus":      {"gateway", "profile"},
+	"sofia global":         {"debug", "siptrace", "standby", "watchdog"},
+	"conference":           {"list", "xml_list"},
+	"fsctl":                {"calibrate_clock", "debug_level", "hupall", "loglevel", "max_sessions", "pause", "resume", "shutdown", "sps"},
+	"uuid_break":           {"all"},
+	"uuid_hold":            {"off", "toggle"},
+	"uuid_record":          {"mask", "start", "stop", "unmask"},
+	"/event":               {"ALL", "BACKGROUND_JOB", "CHANNEL_ANSWER", "CHANNEL_BRIDGE", "CHANNEL_CREATE", "CHANNEL_HANGUP", "CHANNEL_HANGUP_COMPLETE", "CUSTOM", "DTMF", "HEARTBEAT", "json", "plain"},
+	"/log":                 {"alert", "console", "crit", "debug", "err", "info", "notice", "warning"},
+	"fsctl loglevel":       {"alert", "console", "crit", "debug", "err", "info", "notice", "warning"},
+	"sofia profile":        {"external", "internal"},
+	"sofia status profile": {"external", "internal"},
+}
+
+// complete - Returns the completions of the last word of the line, sorted
+func complete(line string) []string {
+	words := strings.Fields(line)
+	word := ""
+	if len(words) > 0 && !strings.HasSuffix(line, " ") {
+		word = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+	// bgapi takes the same commands as api
+	if len(words) > 0 && words[0] == "bgapi" {
+		words = words[1:]
+	}
+
+	candidates := commands
+	if len(words) > 0 {
+		candidates = subcommands[strings.Join(words, " ")]
+	}
+	var matches []string
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, word) {
+			matches = append(matches, candidate)
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
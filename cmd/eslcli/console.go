@@ -0,0 +1,323 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// errInterrupted - Returned by ReadLine when Ctrl-C is pressed on an empty line
+var errInterrupted = errors.New("interrupted")
+
+// console - Serializes output so events and logs printed while the user is typing do not mangle the prompt
+type console struct {
+	lock   sync.Mutex
+	out    io.Writer
+	editor *lineEditor // Set while a terminal prompt is displayed
+}
+
+// lineEditor - Reads lines with history and tab completion when the input is a terminal, or plain lines otherwise
+type lineEditor struct {
+	input    *bufio.Reader
+	file     *os.File
+	console  *console
+	prompt   string
+	complete func(line string) []string
+	raw      bool
+	restore  func()
+
+	// Guarded by the console lock
+	history      []string
+	historyIndex int
+	line         []rune
+	cursor       int
+}
+
+// key - A decoded key press
+type key struct {
+	r    rune
+	name string // Set for special keys such as up or delete
+}
+
+func newConsole(out io.Writer) *console {
+	return &console{out: out}
+}
+
+// Printf - Prints above the prompt
+func (c *console) Printf(format string, args ...interface{}) {
+	c.write(fmt.Sprintf(format, args...))
+}
+
+// Println - Prints above the prompt
+func (c *console) Println(args ...interface{}) {
+	c.write(fmt.Sprintln(args...))
+}
+
+func (c *console) write(text string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.editor == nil {
+		_, _ = io.WriteString(c.out, text)
+		return
+	}
+	// Clear the prompt, print and draw the prompt again below the output
+	_, _ = io.WriteString(c.out, "\r\x1b[K"+text)
+	c.editor.redraw()
+}
+
+func newLineEditor(input io.Reader, console *console, prompt string) *lineEditor {
+	editor := &lineEditor{
+		input:   bufio.NewReader(input),
+		console: console,
+		prompt:  prompt,
+	}
+	editor.file, _ = input.(*os.File)
+	return editor
+}
+
+// start - Switches the terminal to raw mode for line editing, does nothing if the input is not a terminal
+func (e *lineEditor) start() error {
+	if e.file == nil || !isTerminal(int(e.file.Fd())) {
+		return nil
+	}
+	restore, err := makeRaw(int(e.file.Fd()))
+	if err != nil {
+		return err
+	}
+	e.raw = true
+	e.restore = restore
+	return nil
+}
+
+// stop - Restores the terminal
+func (e *lineEditor) stop() {
+	e.console.lock.Lock()
+	defer e.console.lock.Unlock()
+	e.console.editor = nil
+	if e.restore != nil {
+		e.restore()
+		e.restore = nil
+	}
+}
+
+// History - Returns the lines entered so far, oldest first
+func (e *lineEditor) History() []string {
+	e.console.lock.Lock()
+	defer e.console.lock.Unlock()
+	return append([]string(nil), e.history...)
+}
+
+// ReadLine - Reads the next line. Returns io.EOF at the end of the input or on Ctrl-D, and errInterrupted on Ctrl-C
+func (e *lineEditor) ReadLine() (string, error) {
+	if !e.raw {
+		line, err := e.input.ReadString('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			return "", err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		e.addHistory(line)
+		return line, nil
+	}
+
+	e.console.lock.Lock()
+	e.line = nil
+	e.cursor = 0
+	e.historyIndex = len(e.history)
+	e.console.editor = e
+	e.redraw()
+	e.console.lock.Unlock()
+
+	for {
+		pressed, err := e.readKey()
+		if err != nil {
+			e.endPrompt()
+			return "", err
+		}
+		line, done, err := e.handleKey(pressed)
+		if done {
+			e.endPrompt()
+			if err == nil {
+				e.addHistory(line)
+			}
+			return line, err
+		}
+	}
+}
+
+// readKey - Reads a key press, decoding the escape sequences terminals send for arrow and editing keys
+func (e *lineEditor) readKey() (key, error) {
+	r, _, err := e.input.ReadRune()
+	if err != nil || r != 27 {
+		return key{r: r}, err
+	}
+	if next, _, err := e.input.ReadRune(); err != nil || (next != '[' && next != 'O') {
+		return key{r: next}, err
+	}
+	var sequence []rune
+	for {
+		r, _, err = e.input.ReadRune()
+		if err != nil {
+			return key{}, err
+		}
+		sequence = append(sequence, r)
+		// Sequences end with a letter or ~
+		if unicode.IsLetter(r) || r == '~' {
+			break
+		}
+	}
+	names := map[string]string{"A": "up", "B": "down", "C": "right", "D": "left", "H": "home", "F": "end", "1~": "home", "4~": "end", "3~": "delete"}
+	return key{name: names[string(sequence)]}, nil
+}
+
+// handleKey - Applies the key to the line. Returns the line and true once it is complete
+func (e *lineEditor) handleKey(pressed key) (string, bool, error) {
+	e.console.lock.Lock()
+	defer e.console.lock.Unlock()
+
+	switch {
+	case pressed.r == '\r' || pressed.r == '\n':
+		return string(e.line), true, nil
+	case pressed.r == 3: // Ctrl-C
+		if len(e.line) == 0 {
+			return "", true, errInterrupted
+		}
+		e.line, e.cursor = nil, 0
+	case pressed.r == 4: // Ctrl-D
+		if len(e.line) == 0 {
+			return "", true, io.EOF
+		}
+		e.delete(e.cursor)
+	case pressed.r == 127 || pressed.r == 8: // Backspace
+		if e.cursor > 0 {
+			e.cursor--
+			e.delete(e.cursor)
+		}
+	case pressed.r == '\t':
+		e.completeLine()
+	case pressed.r == 1 || pressed.name == "home": // Ctrl-A
+		e.cursor = 0
+	case pressed.r == 5 || pressed.name == "end": // Ctrl-E
+		e.cursor = len(e.line)
+	case pressed.r == 21: // Ctrl-U
+		e.line, e.cursor = append([]rune(nil), e.line[e.cursor:]...), 0
+	case pressed.name == "left":
+		if e.cursor > 0 {
+			e.cursor--
+		}
+	case pressed.name == "right":
+		if e.cursor < len(e.line) {
+			e.cursor++
+		}
+	case pressed.name == "delete":
+		e.delete(e.cursor)
+	case pressed.name == "up":
+		if e.historyIndex > 0 {
+			e.historyIndex--
+			e.line = []rune(e.history[e.historyIndex])
+			e.cursor = len(e.line)
+		}
+	case pressed.name == "down":
+		if e.historyIndex < len(e.history) {
+			e.historyIndex++
+			e.line = nil
+			if e.historyIndex < len(e.history) {
+				e.line = []rune(e.history[e.historyIndex])
+			}
+			e.cursor = len(e.line)
+		}
+	case pressed.r > 0 && unicode.IsPrint(pressed.r):
+		e.insert(string(pressed.r))
+	}
+	e.redraw()
+	return "", false, nil
+}
+
+// completeLine - Completes the word before the cursor, listing the candidates when there is more than one
+func (e *lineEditor) completeLine() {
+	if e.complete == nil {
+		return
+	}
+	before := string(e.line[:e.cursor])
+	word := before[strings.LastIndex(before, " ")+1:]
+	candidates := e.complete(before)
+	switch len(candidates) {
+	case 0:
+		_, _ = io.WriteString(e.console.out, "\a")
+	case 1:
+		e.insert(strings.TrimPrefix(candidates[0], word) + " ")
+	default:
+		if prefix := commonPrefix(candidates); len(prefix) > len(word) {
+			e.insert(strings.TrimPrefix(prefix, word))
+			return
+		}
+		_, _ = io.WriteString(e.console.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+// redraw - Draws the prompt and line, must be called with the console lock held
+func (e *lineEditor) redraw() {
+	output := "\r\x1b[K" + e.prompt + string(e.line)
+	if back := len(e.line) - e.cursor; back > 0 {
+		output += fmt.Sprintf("\x1b[%dD", back)
+	}
+	_, _ = io.WriteString(e.console.out, output)
+}
+
+func (e *lineEditor) endPrompt() {
+	e.console.lock.Lock()
+	defer e.console.lock.Unlock()
+	e.console.editor = nil
+	_, _ = io.WriteString(e.console.out, "\r\n")
+}
+
+func (e *lineEditor) insert(text string) {
+	runes := []rune(text)
+	line := make([]rune, 0, len(e.line)+len(runes))
+	line = append(line, e.line[:e.cursor]...)
+	line = append(line, runes...)
+	e.line = append(line, e.line[e.cursor:]...)
+	e.cursor += len(runes)
+}
+
+func (e *lineEditor) delete(position int) {
+	if position < len(e.line) {
+		e.line = append(e.line[:position], e.line[position+1:]...)
+	}
+}
+
+// addHistory - Adds the line to the history, skipping empty lines and repeats
+func (e *lineEditor) addHistory(line string) {
+	e.console.lock.Lock()
+	defer e.console.lock.Unlock()
+	if len(strings.TrimSpace(line)) == 0 || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+}
+
+func commonPrefix(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	first, last := sorted[0], sorted[len(sorted)-1]
+	i := 0
+	for i < len(first) && i < len(last) && first[i] == last[i] {
+		i++
+	}
+	return first[:i]
+}
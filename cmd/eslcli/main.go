@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+
+// eslcli is an interactive FreeSWITCH ESL client, similar to fs_cli, built on eslgo.
+//
+//	eslcli -H 127.0.0.1 -P 8021 -p ClueCon
+//	eslcli -x "show channels" -x "status"
+//	eslcli -events CHANNEL_CREATE,CHANNEL_HANGUP -json
+//
+// Lines typed at the prompt are sent as api commands, or bgapi commands when they start with bgapi.
+// Lines starting with / control the client, type /help for a list.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/percipia/eslgo"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// config - The command line flags
+type config struct {
+	Host     string
+	Port     int
+	Password string
+	User     string
+	Commands []string      // Executed in order before exiting when set
+	Events   string        // Comma separated event names to display on start, ALL for every event
+	UUID     string        // Only display events of this channel
+	JSON     bool          // Display events as JSON instead of plain text
+	LogLevel string        // Stream FreeSWITCH logs at this level on start
+	Timeout  time.Duration // How long to wait for connecting and for each command
+}
+
+// commandList - A flag that can be given multiple times
+type commandList []string
+
+func (c *commandList) String() string {
+	return strings.Join(*c, "; ")
+}
+
+func (c *commandList) Set(value string) error {
+	*c = append(*c, value)
+	return nil
+}
+
+func main() {
+	cfg, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	} else if err != nil {
+		os.Exit(2)
+	}
+	os.Exit(run(cfg, os.Stdin, os.Stdout, os.Stderr))
+}
+
+func parseFlags(args []string, output io.Writer) (config, error) {
+	cfg := config{}
+	var commands commandList
+	flags := flag.NewFlagSet("eslcli", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&cfg.Host, "H", "127.0.0.1", "FreeSWITCH host")
+	flags.IntVar(&cfg.Port, "P", 8021, "FreeSWITCH event socket port")
+	flags.StringVar(&cfg.Password, "p", "ClueCon", "Event socket password, or the user password with -u")
+	flags.StringVar(&cfg.User, "u", "", "Log in as the directory user with userauth, e.g. 1000@example.com")
+	flags.Var(&commands, "x", "Execute the command and exit, can be given multiple times")
+	flags.StringVar(&cfg.Events, "events", "", "Comma separated events to display, ALL for every event")
+	flags.StringVar(&cfg.UUID, "uuid", "", "Only display events of this channel")
+	flags.BoolVar(&cfg.JSON, "json", false, "Display events as JSON")
+	flags.StringVar(&cfg.LogLevel, "log", "", "Display FreeSWITCH logs at this level, e.g. debug or 7")
+	flags.DurationVar(&cfg.Timeout, "t", 10*time.Second, "Timeout for connecting and for each command")
+	if err := flags.Parse(args); err != nil {
+		return cfg, err
+	}
+	cfg.Commands = commands
+	return cfg, nil
+}
+
+// run - Connects and either executes the commands given with -x or starts the prompt. Returns the exit code
+func run(cfg config, stdin io.Reader, stdout, stderr io.Writer) int {
+	disconnected := make(chan struct{})
+	opts := eslgo.DefaultInboundOptions
+	opts.Logger = eslgo.NilLogger{}
+	opts.Password = cfg.Password
+	opts.User = cfg.User
+	opts.AuthTimeout = cfg.Timeout
+	opts.OnDisconnect = func() {
+		close(disconnected)
+	}
+	conn, err := opts.Dial(net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)))
+	if err != nil {
+		fmt.Fprintln(stderr, "Error connecting:", err)
+		return 1
+	}
+	defer conn.ExitAndClose()
+
+	console := newConsole(stdout)
+	client := newClient(conn, console, cfg.Timeout)
+	client.json = cfg.JSON
+	client.uuid = cfg.UUID
+	if err = client.start(); err != nil {
+		fmt.Fprintln(stderr, "Error subscribing to background jobs:", err)
+		return 1
+	}
+
+	var setup []string
+	if len(cfg.Events) > 0 {
+		setup = append(setup, "/event "+strings.ReplaceAll(cfg.Events, ",", " "))
+	}
+	if len(cfg.LogLevel) > 0 {
+		setup = append(setup, "/log "+cfg.LogLevel)
+	}
+	for _, line := range setup {
+		if _, err = client.execute(line); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+
+	if len(cfg.Commands) > 0 {
+		code := 0
+		for _, line := range cfg.Commands {
+			if _, err = client.execute(line); err != nil {
+				fmt.Fprintln(stderr, err)
+				code = 1
+			}
+		}
+		return code
+	}
+
+	editor := newLineEditor(stdin, console, "eslcli> ")
+	editor.complete = complete
+	if err = editor.start(); err != nil {
+		fmt.Fprintln(stderr, "Error setting up the terminal:", err)
+		return 1
+	}
+	defer editor.stop()
+	return client.repl(editor, disconnected)
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/percipia/eslgo"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFreeSWITCH - Accepts ESL connections, answering commands with respond
+func fakeFreeSWITCH(t *testing.T, respond func(command string) string) (string, int) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				reader := textproto.NewReader(bufio.NewReader(c))
+				_, _ = c.Write([]byte("Content-Type: auth/request\n\n"))
+				for {
+					line, err := reader.ReadLine()
+					if err != nil {
+						return
+					}
+					if _, err = reader.ReadMIMEHeader(); err != nil {
+						return
+					}
+					_, _ = c.Write([]byte(respond(line)))
+				}
+			}()
+		}
+	}()
+	address := listener.Addr().(*net.TCPAddr)
+	return address.IP.String(), address.Port
+}
+
+func apiResponse(body string) string {
+	return fmt.Sprintf("Content-Type: api/response\nContent-Length: %d\n\n%s", len(body), body)
+}
+
+func TestRun_Commands(t *testing.T) {
+	host, port := fakeFreeSWITCH(t, func(command string) string {
+		switch command {
+		case "api status ":
+			return apiResponse("UP 0 years, 0 days\n")
+		case "api bogus ":
+			return apiResponse("-ERR bogus Command not found!\n")
+		}
+		return "Content-Type: command/reply\nReply-Text: +OK\n\n"
+	})
+
+	cfg, err := parseFlags([]string{"-H", host, "-P", strconv.Itoa(port), "-t", "5s", "-x", "status"}, &bytes.Buffer{})
+	assert.Nil(t, err)
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, run(cfg, strings.NewReader(""), &stdout, &stderr))
+	assert.Equal(t, "UP 0 years, 0 days\n", stdout.String())
+
+	cfg.Commands = []string{"bogus"}
+	stdout.Reset()
+	assert.Equal(t, 1, run(cfg, strings.NewReader(""), &stdout, &stderr))
+	assert.Equal(t, "-ERR bogus Command not found!\n", stdout.String())
+	assert.Contains(t, stderr.String(), "Command not found")
+}
+
+func TestRun_Prompt(t *testing.T) {
+	host, port := fakeFreeSWITCH(t, func(command string) string {
+		if command == "api version " {
+			return apiResponse("FreeSWITCH Version 1.10.12\n")
+		}
+		return "Content-Type: command/reply\nReply-Text: +OK\n\n"
+	})
+
+	cfg, err := parseFlags([]string{"-H", host, "-P", strconv.Itoa(port)}, &bytes.Buffer{})
+	assert.Nil(t, err)
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 0, run(cfg, strings.NewReader("version\n/history\n/exit\n"), &stdout, &stderr))
+	assert.Equal(t, "FreeSWITCH Version 1.10.12\n   1  version\n   2  /history\n", stdout.String())
+}
+
+func TestClient_BackgroundJob(t *testing.T) {
+	host, port := fakeFreeSWITCH(t, func(command string) string {
+		switch {
+		case strings.HasPrefix(command, "bgapi bogus"):
+			return "Content-Type: command/reply\nReply-Text: -ERR bogus Command not found!\n\n"
+		case command == "api status ":
+			// Finishes the jobs, only the job of the accepted bgapi may be displayed
+			body := "Event-Name: BACKGROUND_JOB\nJob-UUID: job\nContent-Length: 3\n\n+OK"
+			return fmt.Sprintf("Content-Type: text/event-plain\nContent-Length: %d\n\n%s", len(body), body) + apiResponse("UP\n")
+		}
+		return "Content-Type: command/reply\nReply-Text: +OK\n\n"
+	})
+	opts := eslgo.DefaultInboundOptions
+	opts.Logger = eslgo.NilLogger{}
+	conn, err := opts.Dial(net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var stdout bytes.Buffer
+	client := newClient(conn, newConsole(&stdout), 5*time.Second)
+	// A rejected bgapi stops waiting for its job right away
+	assert.Error(t, client.api("bgapi bogus"))
+	stop := client.displayJob("job")
+	stop()
+	assert.Nil(t, client.api("status"))
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, "-ERR bogus Command not found!\nUP\n", stdout.String())
+}
+
+func TestLineEditor(t *testing.T) {
+	var output bytes.Buffer
+	editor := newLineEditor(strings.NewReader("sh\tchan\t\rx\x7fuptime\r\x1b[A\x1b[A\x01\x1b[C\x1b[C\x1b[C\x1b[C\x1b[Cx\r\x03\x03"), newConsole(&output), "> ")
+	editor.complete = complete
+	editor.raw = true
+
+	line, err := editor.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "show channels ", line)
+	line, err = editor.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "uptime", line)
+	// Up twice goes back to the first line, then the cursor is moved to the start of the second word
+	line, err = editor.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "show xchannels ", line)
+	_, err = editor.ReadLine()
+	assert.Equal(t, errInterrupted, err)
+	assert.Equal(t, []string{"show channels ", "uptime", "show xchannels "}, editor.History())
+}
+
+func TestComplete(t *testing.T) {
+	assert.Equal(t, []string{"status"}, complete("stat"))
+	assert.Equal(t, []string{"uuid_kill"}, complete("bgapi uuid_k"))
+	assert.Equal(t, []string{"channels", "chat"}, complete("show ch"))
+	assert.Equal(t, []string{"gateway", "profile"}, complete("sofia status "))
+	assert.Equal(t, []string{"debug"}, complete("/log d"))
+	assert.Empty(t, complete("status x"))
+	assert.Equal(t, "cha", commonPrefix([]string{"channels", "chat"}))
+}
+
+func TestFormatEvent(t *testing.T) {
+	event := &eslgo.Event{
+		Headers: textproto.MIMEHeader{
+			"Event-Name":    {"CHANNEL_CREATE"},
+			"Unique-Id":     {"channel"},
+			"Caller-Caller": {"Front%20Desk"},
+		},
+		JSON: []byte("{\n  \"Event-Name\": \"CHANNEL_CREATE\",\n  \"Unique-ID\": \"channel\",\n  \"Caller-Caller\": \"Front Desk\"\n}"),
+	}
+	assert.Equal(t, "Caller-Caller: Front Desk\nEvent-Name: CHANNEL_CREATE\nUnique-Id: channel\n", formatPlainEvent(event))
+	assert.Equal(t, `{"Event-Name":"CHANNEL_CREATE","Unique-ID":"channel","Caller-Caller":"Front Desk"}`, formatJSONEvent(event))
+
+	assert.True(t, matchEvent(event, map[string]bool{}, ""))
+	assert.True(t, matchEvent(event, map[string]bool{"CHANNEL_CREATE": true}, "channel"))
+	assert.False(t, matchEvent(event, map[string]bool{"CHANNEL_HANGUP": true}, ""))
+	assert.False(t, matchEvent(event, map[string]bool{}, "other"))
+
+	level, err := parseLogLevel("warning")
+	assert.Nil(t, err)
+	assert.Equal(t, eslgo.LogLevelWarning, level)
+	_, err = parseLogLevel("loud")
+	assert.Error(t, err)
+	assert.Equal(t, "[ERR] mod_sofia.c:12 failed", formatLogRecord(&eslgo.LogRecord{Level: eslgo.LogLevelErr, File: "mod_sofia.c", Line: 12, Text: "failed"}))
+}
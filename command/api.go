@@ -16,10 +16,14 @@ type API struct {
 	Command    string
 	Arguments  string
 	Background bool
+	JobUUID    string // Optional Job-UUID for background commands, lets the BACKGROUND_JOB event be matched before the reply arrives
 }
 
 func (api API) BuildMessage() string {
 	if api.Background {
+		if len(api.JobUUID) > 0 {
+			return fmt.Sprintf("bgapi %s %s\r\nJob-UUID: %s", api.Command, api.Arguments, api.JobUUID)
+		}
 		return fmt.Sprintf("bgapi %s %s", api.Command, api.Arguments)
 	}
 	return fmt.Sprintf("api %s %s", api.Command, api.Arguments)
@@ -18,6 +18,7 @@ import (
 const (
 	TestAPIMessage   = `api originate user/100 &park()`
 	TestBGAPIMessage = `bgapi originate user/100 &park()`
+	TestBGAPIJobUUID = "bgapi originate user/100 &park()\r\nJob-UUID: 1234"
 )
 
 func TestAPI_BuildMessage(t *testing.T) {
@@ -36,3 +37,13 @@ func TestAPI_BuildMessage_BG(t *testing.T) {
 	}
 	assert.Equal(t, TestBGAPIMessage, api.BuildMessage())
 }
+
+func TestAPI_BuildMessage_JobUUID(t *testing.T) {
+	api := API{
+		Command:    "originate",
+		Arguments:  "user/100 &park()",
+		Background: true,
+		JobUUID:    "1234",
+	}
+	assert.Equal(t, TestBGAPIJobUUID, api.BuildMessage())
+}
@@ -12,13 +12,18 @@ package command
 
 import "fmt"
 
+// Auth - Authenticates with the event socket password, or with a directory user using userauth when User is set
 type Auth struct {
 	User     string
+	Domain   string // Optional domain of the user for userauth, the User can also contain it e.g. 1000@example.com
 	Password string
 }
 
 func (auth Auth) BuildMessage() string {
 	if len(auth.User) > 0 {
+		if len(auth.Domain) > 0 {
+			return fmt.Sprintf("userauth %s@%s:%s", auth.User, auth.Domain, auth.Password)
+		}
 		return fmt.Sprintf("userauth %s:%s", auth.User, auth.Password)
 	}
 	return fmt.Sprintf("auth %s", auth.Password)
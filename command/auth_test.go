@@ -16,8 +16,9 @@ import (
 )
 
 const (
-	TestAuthMessage     = `auth testing123`
-	TestUserAuthMessage = `userauth testuser:testing123`
+	TestAuthMessage       = `auth testing123`
+	TestUserAuthMessage   = `userauth testuser:testing123`
+	TestDomainAuthMessage = `userauth 1000@example.com:testing123`
 )
 
 func TestAuth_BuildMessage(t *testing.T) {
@@ -34,3 +35,12 @@ func TestAuth_BuildMessage_User(t *testing.T) {
 	}
 	assert.Equal(t, TestUserAuthMessage, auth.BuildMessage())
 }
+
+func TestAuth_BuildMessage_Domain(t *testing.T) {
+	auth := Auth{
+		User:     "1000",
+		Domain:   "example.com",
+		Password: "testing123",
+	}
+	assert.Equal(t, TestDomainAuthMessage, auth.BuildMessage())
+}
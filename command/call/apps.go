@@ -0,0 +1,141 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package call
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Answer - Answers the channel, the sendmsg equivalent of uuid_answer
+type Answer struct {
+	UUID    string
+	Sync    bool
+	SyncPri bool
+}
+
+// PreAnswer - Sends early media to the channel, the sendmsg equivalent of uuid_pre_answer
+type PreAnswer Answer
+
+// Park - Parks the channel, the sendmsg equivalent of uuid_park
+type Park Answer
+
+// Break - Stops the application currently running on the channel, the sendmsg equivalent of uuid_break. All also clears any queued applications
+type Break struct {
+	UUID    string
+	All     bool
+	Sync    bool
+	SyncPri bool
+}
+
+// Broadcast - Plays the path to the channel, the sendmsg equivalent of uuid_broadcast on the channel itself
+// The path can also be an application in the uuid_broadcast "app::args" format
+type Broadcast struct {
+	UUID    string
+	Path    string
+	Sync    bool
+	SyncPri bool
+}
+
+// Displace - Mixes the file into the audio of the channel without interrupting it, the sendmsg equivalent of uuid_displace
+// Stop removes a running displace of the same path
+type Displace struct {
+	UUID      string
+	Path      string
+	Stop      bool
+	Mux       bool          // Mix the file with the channel audio instead of replacing it
+	Loop      bool          // Loop the file until stopped
+	TimeLimit time.Duration // Stop after this long, sent in milliseconds
+	Sync      bool
+	SyncPri   bool
+}
+
+func (a Answer) BuildMessage() string {
+	return a.execute("answer")
+}
+
+func (p PreAnswer) BuildMessage() string {
+	return Answer(p).execute("pre_answer")
+}
+
+func (p Park) BuildMessage() string {
+	return Answer(p).execute("park")
+}
+
+func (a Answer) execute(app string) string {
+	e := Execute{
+		UUID:    a.UUID,
+		AppName: app,
+		Sync:    a.Sync,
+		SyncPri: a.SyncPri,
+	}
+	return e.BuildMessage()
+}
+
+func (b Break) BuildMessage() string {
+	e := Execute{
+		UUID:    b.UUID,
+		AppName: "break",
+		Sync:    b.Sync,
+		SyncPri: b.SyncPri,
+	}
+	if b.All {
+		e.AppArgs = "all"
+	}
+	return e.BuildMessage()
+}
+
+func (b Broadcast) BuildMessage() string {
+	e := Execute{
+		UUID:    b.UUID,
+		AppName: "playback",
+		AppArgs: b.Path,
+		Sync:    b.Sync,
+		SyncPri: b.SyncPri,
+	}
+	if app, args, ok := strings.Cut(b.Path, "::"); ok {
+		e.AppName = app
+		e.AppArgs = args
+	}
+	return e.BuildMessage()
+}
+
+func (d Displace) BuildMessage() string {
+	e := Execute{
+		UUID:    d.UUID,
+		AppName: "displace_session",
+		AppArgs: d.Path,
+		Sync:    d.Sync,
+		SyncPri: d.SyncPri,
+	}
+	if d.Stop {
+		e.AppName = "stop_displace_session"
+		return e.BuildMessage()
+	}
+
+	var flags strings.Builder
+	if d.Mux {
+		flags.WriteString("m")
+	}
+	if d.Loop {
+		flags.WriteString("l")
+	}
+	args := []string{d.Path}
+	if flags.Len() > 0 {
+		args = append(args, flags.String())
+	}
+	if d.TimeLimit > 0 {
+		args = append(args, fmt.Sprintf("+%d", d.TimeLimit.Milliseconds()))
+	}
+	e.AppArgs = strings.Join(args, " ")
+	return e.BuildMessage()
+}
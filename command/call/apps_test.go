@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package call
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	TestAnswerMessage = strings.ReplaceAll(`sendmsg none
+Call-Command: execute
+Event-Lock: true
+Execute-App-Name: answer
+Loops: 1`, "\n", "\r\n")
+	TestPreAnswerMessage = strings.ReplaceAll(`sendmsg none
+Call-Command: execute
+Execute-App-Name: pre_answer
+Loops: 1`, "\n", "\r\n")
+	TestParkMessage = strings.ReplaceAll(`sendmsg none
+Call-Command: execute
+Execute-App-Name: park
+Loops: 1`, "\n", "\r\n")
+	TestBreakMessage = strings.ReplaceAll(`sendmsg none
+Call-Command: execute
+Execute-App-Arg: all
+Execute-App-Name: break
+Loops: 1`, "\n", "\r\n")
+	TestBroadcastMessage = strings.ReplaceAll(`sendmsg none
+Call-Command: execute
+Execute-App-Arg: /tmp/hold.wav
+Execute-App-Name: playback
+Loops: 1`, "\n", "\r\n")
+	TestBroadcastAppMessage = strings.ReplaceAll(`sendmsg none
+Call-Command: execute
+Execute-App-Arg: 3 300 30
+Execute-App-Name: gentones
+Loops: 1`, "\n", "\r\n")
+	TestDisplaceMessage = strings.ReplaceAll(`sendmsg none
+Call-Command: execute
+Execute-App-Arg: /tmp/beep.wav ml +30000
+Execute-App-Name: displace_session
+Loops: 1`, "\n", "\r\n")
+	TestStopDisplaceMessage = strings.ReplaceAll(`sendmsg none
+Call-Command: execute
+Execute-App-Arg: /tmp/beep.wav
+Execute-App-Name: stop_displace_session
+Loops: 1`, "\n", "\r\n")
+)
+
+func TestAnswer_BuildMessage(t *testing.T) {
+	assert.Equal(t, TestAnswerMessage, Answer{UUID: "none", Sync: true}.BuildMessage())
+	assert.Equal(t, TestPreAnswerMessage, PreAnswer{UUID: "none"}.BuildMessage())
+	assert.Equal(t, TestParkMessage, Park{UUID: "none"}.BuildMessage())
+}
+
+func TestBreak_BuildMessage(t *testing.T) {
+	assert.Equal(t, TestBreakMessage, Break{UUID: "none", All: true}.BuildMessage())
+}
+
+func TestBroadcast_BuildMessage(t *testing.T) {
+	assert.Equal(t, TestBroadcastMessage, Broadcast{UUID: "none", Path: "/tmp/hold.wav"}.BuildMessage())
+	assert.Equal(t, TestBroadcastAppMessage, Broadcast{UUID: "none", Path: "gentones::3 300 30"}.BuildMessage())
+}
+
+func TestDisplace_BuildMessage(t *testing.T) {
+	assert.Equal(t, TestDisplaceMessage, Displace{
+		UUID:      "none",
+		Path:      "/tmp/beep.wav",
+		Mux:       true,
+		Loop:      true,
+		TimeLimit: 30 * time.Second,
+	}.BuildMessage())
+	assert.Equal(t, TestStopDisplaceMessage, Displace{UUID: "none", Path: "/tmp/beep.wav", Stop: true}.BuildMessage())
+}
@@ -0,0 +1,362 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package call
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HangupCause - A FreeSWITCH hangup cause name, e.g. NORMAL_CLEARING. See https://freeswitch.org/confluence/display/FREESWITCH/Hangup+Cause+Code+Table
+type HangupCause string
+
+const (
+	CauseNone                        HangupCause = "NONE"
+	CauseUnallocatedNumber           HangupCause = "UNALLOCATED_NUMBER"
+	CauseNoRouteTransitNet           HangupCause = "NO_ROUTE_TRANSIT_NET"
+	CauseNoRouteDestination          HangupCause = "NO_ROUTE_DESTINATION"
+	CauseChannelUnacceptable         HangupCause = "CHANNEL_UNACCEPTABLE"
+	CauseCallAwardedDelivered        HangupCause = "CALL_AWARDED_DELIVERED"
+	CauseNormalClearing              HangupCause = "NORMAL_CLEARING"
+	CauseUserBusy                    HangupCause = "USER_BUSY"
+	CauseNoUserResponse              HangupCause = "NO_USER_RESPONSE"
+	CauseNoAnswer                    HangupCause = "NO_ANSWER"
+	CauseSubscriberAbsent            HangupCause = "SUBSCRIBER_ABSENT"
+	CauseCallRejected                HangupCause = "CALL_REJECTED"
+	CauseNumberChanged               HangupCause = "NUMBER_CHANGED"
+	CauseRedirectionToNewDestination HangupCause = "REDIRECTION_TO_NEW_DESTINATION"
+	CauseExchangeRoutingError        HangupCause = "EXCHANGE_ROUTING_ERROR"
+	CauseDestinationOutOfOrder       HangupCause = "DESTINATION_OUT_OF_ORDER"
+	CauseInvalidNumberFormat         HangupCause = "INVALID_NUMBER_FORMAT"
+	CauseFacilityRejected            HangupCause = "FACILITY_REJECTED"
+	CauseResponseToStatusEnquiry     HangupCause = "RESPONSE_TO_STATUS_ENQUIRY"
+	CauseNormalUnspecified           HangupCause = "NORMAL_UNSPECIFIED"
+	CauseNormalCircuitCongestion     HangupCause = "NORMAL_CIRCUIT_CONGESTION"
+	CauseNetworkOutOfOrder           HangupCause = "NETWORK_OUT_OF_ORDER"
+	CauseNormalTemporaryFailure      HangupCause = "NORMAL_TEMPORARY_FAILURE"
+	CauseSwitchCongestion            HangupCause = "SWITCH_CONGESTION"
+	CauseAccessInfoDiscarded         HangupCause = "ACCESS_INFO_DISCARDED"
+	CauseRequestedChanUnavail        HangupCause = "REQUESTED_CHAN_UNAVAIL"
+	CausePreEmpted                   HangupCause = "PRE_EMPTED"
+	CauseFacilityNotSubscribed       HangupCause = "FACILITY_NOT_SUBSCRIBED"
+	CauseOutgoingCallBarred          HangupCause = "OUTGOING_CALL_BARRED"
+	CauseIncomingCallBarred          HangupCause = "INCOMING_CALL_BARRED"
+	CauseBearerCapabilityNotAuth     HangupCause = "BEARERCAPABILITY_NOTAUTH"
+	CauseBearerCapabilityNotAvail    HangupCause = "BEARERCAPABILITY_NOTAVAIL"
+	CauseServiceUnavailable          HangupCause = "SERVICE_UNAVAILABLE"
+	CauseBearerCapabilityNotImpl     HangupCause = "BEARERCAPABILITY_NOTIMPL"
+	CauseChanNotImplemented          HangupCause = "CHAN_NOT_IMPLEMENTED"
+	CauseFacilityNotImplemented      HangupCause = "FACILITY_NOT_IMPLEMENTED"
+	CauseServiceNotImplemented       HangupCause = "SERVICE_NOT_IMPLEMENTED"
+	CauseInvalidCallReference        HangupCause = "INVALID_CALL_REFERENCE"
+	CauseIncompatibleDestination     HangupCause = "INCOMPATIBLE_DESTINATION"
+	CauseInvalidMsgUnspecified       HangupCause = "INVALID_MSG_UNSPECIFIED"
+	CauseMandatoryIEMissing          HangupCause = "MANDATORY_IE_MISSING"
+	CauseMessageTypeNonExist         HangupCause = "MESSAGE_TYPE_NONEXIST"
+	CauseWrongMessage                HangupCause = "WRONG_MESSAGE"
+	CauseIENonExist                  HangupCause = "IE_NONEXIST"
+	CauseInvalidIEContents           HangupCause = "INVALID_IE_CONTENTS"
+	CauseWrongCallState              HangupCause = "WRONG_CALL_STATE"
+	CauseRecoveryOnTimerExpire       HangupCause = "RECOVERY_ON_TIMER_EXPIRE"
+	CauseMandatoryIELengthError      HangupCause = "MANDATORY_IE_LENGTH_ERROR"
+	CauseProtocolError               HangupCause = "PROTOCOL_ERROR"
+	CauseInterworking                HangupCause = "INTERWORKING"
+	CauseSuccess                     HangupCause = "SUCCESS"
+	CauseOriginatorCancel            HangupCause = "ORIGINATOR_CANCEL"
+	CauseCrash                       HangupCause = "CRASH"
+	CauseSystemShutdown              HangupCause = "SYSTEM_SHUTDOWN"
+	CauseLoseRace                    HangupCause = "LOSE_RACE"
+	CauseManagerRequest              HangupCause = "MANAGER_REQUEST"
+	CauseBlindTransfer               HangupCause = "BLIND_TRANSFER"
+	CauseAttendedTransfer            HangupCause = "ATTENDED_TRANSFER"
+	CauseAllottedTimeout             HangupCause = "ALLOTTED_TIMEOUT"
+	CauseUserChallenge               HangupCause = "USER_CHALLENGE"
+	CauseMediaTimeout                HangupCause = "MEDIA_TIMEOUT"
+	CausePickedOff                   HangupCause = "PICKED_OFF"
+	CauseUserNotRegistered           HangupCause = "USER_NOT_REGISTERED"
+	CauseProgressTimeout             HangupCause = "PROGRESS_TIMEOUT"
+	CauseInvalidGateway              HangupCause = "INVALID_GATEWAY"
+	CauseGatewayDown                 HangupCause = "GATEWAY_DOWN"
+	CauseInvalidURL                  HangupCause = "INVALID_URL"
+	CauseInvalidProfile              HangupCause = "INVALID_PROFILE"
+	CauseNoPickup                    HangupCause = "NO_PICKUP"
+	CauseSRTPReadError               HangupCause = "SRTP_READ_ERROR"
+	CauseBowout                      HangupCause = "BOWOUT"
+	CauseBusyEverywhere              HangupCause = "BUSY_EVERYWHERE"
+	CauseDecline                     HangupCause = "DECLINE"
+	CauseDoesNotExistAnywhere        HangupCause = "DOES_NOT_EXIST_ANYWHERE"
+	CauseNotAcceptable               HangupCause = "NOT_ACCEPTABLE"
+	CauseUnwanted                    HangupCause = "UNWANTED"
+	CauseNoIdentity                  HangupCause = "NO_IDENTITY"
+	CauseBadIdentityInfo             HangupCause = "BAD_IDENTITY_INFO"
+	CauseUnsupportedCertificate      HangupCause = "UNSUPPORTED_CERTIFICATE"
+	CauseInvalidIdentity             HangupCause = "INVALID_IDENTITY"
+	CauseStaleDate                   HangupCause = "STALE_DATE"
+	CauseRejectAll                   HangupCause = "REJECT_ALL"
+)
+
+// hangupCauseCodes - The FreeSWITCH numeric code for each cause, these are the Q.850 cause codes for causes up to 127
+var hangupCauseCodes = map[HangupCause]int{
+	CauseNone:                        0,
+	CauseUnallocatedNumber:           1,
+	CauseNoRouteTransitNet:           2,
+	CauseNoRouteDestination:          3,
+	CauseChannelUnacceptable:         6,
+	CauseCallAwardedDelivered:        7,
+	CauseNormalClearing:              16,
+	CauseUserBusy:                    17,
+	CauseNoUserResponse:              18,
+	CauseNoAnswer:                    19,
+	CauseSubscriberAbsent:            20,
+	CauseCallRejected:                21,
+	CauseNumberChanged:               22,
+	CauseRedirectionToNewDestination: 23,
+	CauseExchangeRoutingError:        25,
+	CauseDestinationOutOfOrder:       27,
+	CauseInvalidNumberFormat:         28,
+	CauseFacilityRejected:            29,
+	CauseResponseToStatusEnquiry:     30,
+	CauseNormalUnspecified:           31,
+	CauseNormalCircuitCongestion:     34,
+	CauseNetworkOutOfOrder:           38,
+	CauseNormalTemporaryFailure:      41,
+	CauseSwitchCongestion:            42,
+	CauseAccessInfoDiscarded:         43,
+	CauseRequestedChanUnavail:        44,
+	CausePreEmpted:                   45,
+	CauseFacilityNotSubscribed:       50,
+	CauseOutgoingCallBarred:          52,
+	CauseIncomingCallBarred:          54,
+	CauseBearerCapabilityNotAuth:     57,
+	CauseBearerCapabilityNotAvail:    58,
+	CauseServiceUnavailable:          63,
+	CauseBearerCapabilityNotImpl:     65,
+	CauseChanNotImplemented:          66,
+	CauseFacilityNotImplemented:      69,
+	CauseServiceNotImplemented:       79,
+	CauseInvalidCallReference:        81,
+	CauseIncompatibleDestination:     88,
+	CauseInvalidMsgUnspecified:       95,
+	CauseMandatoryIEMissing:          96,
+	CauseMessageTypeNonExist:         97,
+	CauseWrongMessage:                98,
+	CauseIENonExist:                  99,
+	CauseInvalidIEContents:           100,
+	CauseWrongCallState:              101,
+	CauseRecoveryOnTimerExpire:       102,
+	CauseMandatoryIELengthError:      103,
+	CauseProtocolError:               111,
+	CauseInterworking:                127,
+	CauseSuccess:                     142,
+	CauseOriginatorCancel:            487,
+	CauseCrash:                       700,
+	CauseSystemShutdown:              701,
+	CauseLoseRace:                    702,
+	CauseManagerRequest:              703,
+	CauseBlindTransfer:               800,
+	CauseAttendedTransfer:            801,
+	CauseAllottedTimeout:             802,
+	CauseUserChallenge:               803,
+	CauseMediaTimeout:                804,
+	CausePickedOff:                   805,
+	CauseUserNotRegistered:           806,
+	CauseProgressTimeout:             807,
+	CauseInvalidGateway:              808,
+	CauseGatewayDown:                 809,
+	CauseInvalidURL:                  810,
+	CauseInvalidProfile:              811,
+	CauseNoPickup:                    812,
+	CauseSRTPReadError:               813,
+	CauseBowout:                      814,
+	CauseBusyEverywhere:              815,
+	CauseDecline:                     816,
+	CauseDoesNotExistAnywhere:        817,
+	CauseNotAcceptable:               818,
+	CauseUnwanted:                    819,
+	CauseNoIdentity:                  820,
+	CauseBadIdentityInfo:             821,
+	CauseUnsupportedCertificate:      822,
+	CauseInvalidIdentity:             823,
+	CauseStaleDate:                   824,
+	CauseRejectAll:                   825,
+}
+
+// hangupCauseSIPStatus - The SIP response status FreeSWITCH sends when hanging up a SIP channel with the cause
+var hangupCauseSIPStatus = map[HangupCause]int{
+	CauseUnallocatedNumber:           404,
+	CauseNoRouteTransitNet:           404,
+	CauseNoRouteDestination:          404,
+	CauseUserBusy:                    486,
+	CauseNoUserResponse:              408,
+	CauseNoAnswer:                    480,
+	CauseSubscriberAbsent:            480,
+	CauseCallRejected:                603,
+	CauseNumberChanged:               410,
+	CauseRedirectionToNewDestination: 410,
+	CauseExchangeRoutingError:        483,
+	CauseDestinationOutOfOrder:       502,
+	CauseInvalidNumberFormat:         484,
+	CauseFacilityRejected:            501,
+	CauseNormalUnspecified:           480,
+	CauseNormalCircuitCongestion:     503,
+	CauseNetworkOutOfOrder:           502,
+	CauseNormalTemporaryFailure:      503,
+	CauseSwitchCongestion:            503,
+	CauseRequestedChanUnavail:        503,
+	CauseOutgoingCallBarred:          403,
+	CauseIncomingCallBarred:          403,
+	CauseBearerCapabilityNotAuth:     403,
+	CauseBearerCapabilityNotAvail:    503,
+	CauseServiceUnavailable:          503,
+	CauseBearerCapabilityNotImpl:     488,
+	CauseFacilityNotImplemented:      501,
+	CauseServiceNotImplemented:       501,
+	CauseIncompatibleDestination:     488,
+	CauseRecoveryOnTimerExpire:       504,
+	CauseInterworking:                500,
+	CauseOriginatorCancel:            487,
+	CauseAllottedTimeout:             408,
+	CauseUserNotRegistered:           480,
+	CauseProgressTimeout:             408,
+	CauseInvalidGateway:              502,
+	CauseGatewayDown:                 503,
+	CauseNoPickup:                    480,
+	CauseBusyEverywhere:              600,
+	CauseDecline:                     603,
+	CauseDoesNotExistAnywhere:        604,
+	CauseNotAcceptable:               606,
+	CauseUnwanted:                    607,
+	CauseNoIdentity:                  428,
+	CauseBadIdentityInfo:             429,
+	CauseUnsupportedCertificate:      437,
+	CauseInvalidIdentity:             438,
+	CauseStaleDate:                   403,
+}
+
+// sipStatusHangupCause - The cause FreeSWITCH uses when a SIP channel is ended with the SIP response status
+var sipStatusHangupCause = map[int]HangupCause{
+	200: CauseNormalClearing,
+	401: CauseCallRejected,
+	402: CauseCallRejected,
+	403: CauseCallRejected,
+	407: CauseCallRejected,
+	603: CauseCallRejected,
+	608: CauseCallRejected,
+	404: CauseUnallocatedNumber,
+	485: CauseNoRouteDestination,
+	604: CauseNoRouteDestination,
+	408: CauseRecoveryOnTimerExpire,
+	504: CauseRecoveryOnTimerExpire,
+	410: CauseNumberChanged,
+	413: CauseInterworking,
+	414: CauseInterworking,
+	416: CauseInterworking,
+	420: CauseInterworking,
+	421: CauseInterworking,
+	423: CauseInterworking,
+	505: CauseInterworking,
+	513: CauseInterworking,
+	480: CauseNoUserResponse,
+	400: CauseNormalTemporaryFailure,
+	481: CauseNormalTemporaryFailure,
+	500: CauseNormalTemporaryFailure,
+	503: CauseNormalTemporaryFailure,
+	486: CauseUserBusy,
+	600: CauseUserBusy,
+	484: CauseInvalidNumberFormat,
+	488: CauseIncompatibleDestination,
+	606: CauseIncompatibleDestination,
+	502: CauseNetworkOutOfOrder,
+	405: CauseServiceUnavailable,
+	406: CauseServiceNotImplemented,
+	415: CauseServiceNotImplemented,
+	501: CauseServiceNotImplemented,
+	482: CauseExchangeRoutingError,
+	483: CauseExchangeRoutingError,
+	487: CauseOriginatorCancel,
+	428: CauseNoIdentity,
+	429: CauseBadIdentityInfo,
+	437: CauseUnsupportedCertificate,
+	438: CauseInvalidIdentity,
+	607: CauseUnwanted,
+}
+
+var hangupCausesByCode = func() map[int]HangupCause {
+	causes := make(map[int]HangupCause, len(hangupCauseCodes))
+	for cause, code := range hangupCauseCodes {
+		causes[code] = cause
+	}
+	return causes
+}()
+
+// ParseHangupCause - Parses a hangup cause name, returns an error for unknown causes such as typos
+func ParseHangupCause(cause string) (HangupCause, error) {
+	parsed := HangupCause(strings.ToUpper(strings.TrimSpace(cause)))
+	if !parsed.Valid() {
+		return parsed, fmt.Errorf("unknown hangup cause %q", cause)
+	}
+	return parsed, nil
+}
+
+// HangupCauseFromQ850 - Returns the cause for the Q.850 or FreeSWITCH numeric code, e.g. from variable_hangup_cause_q850
+func HangupCauseFromQ850(code int) (HangupCause, bool) {
+	cause, ok := hangupCausesByCode[code]
+	return cause, ok
+}
+
+// HangupCauseFromSIPStatus - Returns the cause FreeSWITCH uses for the SIP response status, e.g. from variable_sip_term_status
+func HangupCauseFromSIPStatus(status int) HangupCause {
+	if cause, ok := sipStatusHangupCause[status]; ok {
+		return cause
+	}
+	if status >= 200 && status < 300 {
+		return CauseNormalClearing
+	}
+	return CauseNormalUnspecified
+}
+
+// Valid - Returns true if the cause is a known FreeSWITCH hangup cause
+func (h HangupCause) Valid() bool {
+	_, ok := hangupCauseCodes[h]
+	return ok
+}
+
+// Q850 - Returns the Q.850 cause code, FreeSWITCH specific causes above 127 return their FreeSWITCH code. Returns -1 for unknown causes
+func (h HangupCause) Q850() int {
+	if code, ok := hangupCauseCodes[h]; ok {
+		return code
+	}
+	return -1
+}
+
+// SIPStatus - Returns the SIP response status FreeSWITCH uses for the cause. Returns 0 for causes that are not failures such as NORMAL_CLEARING
+func (h HangupCause) SIPStatus() int {
+	if status, ok := hangupCauseSIPStatus[h]; ok {
+		return status
+	}
+	switch h {
+	case CauseNone, CauseNormalClearing, CauseSuccess:
+		return 0
+	}
+	// FreeSWITCH falls back to Temporarily Unavailable
+	return 480
+}
+
+// String - Implement the Stringer interface
+func (h HangupCause) String() string {
+	return string(h)
+}
+
+// GoString - Implement the GoStringer interface for pretty printing (%#v)
+func (h HangupCause) GoString() string {
+	return string(h) + "(" + strconv.Itoa(h.Q850()) + ")"
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package call
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseHangupCause(t *testing.T) {
+	cause, err := ParseHangupCause("normal_clearing")
+	assert.Nil(t, err)
+	assert.Equal(t, CauseNormalClearing, cause)
+
+	_, err = ParseHangupCause("NORMAL_CLEARNG")
+	assert.Error(t, err)
+}
+
+func TestHangupCause_Q850(t *testing.T) {
+	assert.Equal(t, 16, CauseNormalClearing.Q850())
+	assert.Equal(t, 17, CauseUserBusy.Q850())
+	assert.Equal(t, 487, CauseOriginatorCancel.Q850())
+	assert.Equal(t, -1, HangupCause("NORMAL_CLEARNG").Q850())
+
+	cause, ok := HangupCauseFromQ850(19)
+	assert.True(t, ok)
+	assert.Equal(t, CauseNoAnswer, cause)
+	_, ok = HangupCauseFromQ850(4)
+	assert.False(t, ok)
+}
+
+func TestHangupCause_SIPStatus(t *testing.T) {
+	assert.Equal(t, 486, CauseUserBusy.SIPStatus())
+	assert.Equal(t, 404, CauseUnallocatedNumber.SIPStatus())
+	assert.Equal(t, 0, CauseNormalClearing.SIPStatus())
+	assert.Equal(t, 480, CauseMediaTimeout.SIPStatus())
+
+	assert.Equal(t, CauseUserBusy, HangupCauseFromSIPStatus(486))
+	assert.Equal(t, CauseCallRejected, HangupCauseFromSIPStatus(403))
+	assert.Equal(t, CauseNormalClearing, HangupCauseFromSIPStatus(200))
+	assert.Equal(t, CauseNormalUnspecified, HangupCauseFromSIPStatus(499))
+}
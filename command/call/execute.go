@@ -90,7 +90,7 @@ func (e *Execute) BuildMessage() string {
 		sendMsg.Headers.Set("content-type", "text/plain")
 		sendMsg.Headers.Set("content-length", strconv.Itoa(len(e.AppArgs)))
 		sendMsg.Body = e.AppArgs
-	} else {
+	} else if len(e.AppArgs) > 0 {
 		sendMsg.Headers.Set("execute-app-arg", e.AppArgs)
 	}
 
@@ -17,7 +17,7 @@ import (
 
 type Hangup struct {
 	UUID    string
-	Cause   string
+	Cause   HangupCause
 	Sync    bool
 	SyncPri bool
 }
@@ -30,7 +30,7 @@ func (h Hangup) BuildMessage() string {
 		SyncPri: h.SyncPri,
 	}
 	sendMsg.Headers.Set("call-command", "hangup")
-	sendMsg.Headers.Set("hangup-cause", h.Cause)
+	sendMsg.Headers.Set("hangup-cause", h.Cause.String())
 
 	return sendMsg.BuildMessage()
 }
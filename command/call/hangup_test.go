@@ -23,7 +23,7 @@ Hangup-Cause: NORMAL_CLEARING`, "\n", "\r\n")
 func TestHangup_BuildMessage(t *testing.T) {
 	hangup := Hangup{
 		UUID:  "none",
-		Cause: "NORMAL_CLEARING",
+		Cause: CauseNormalClearing,
 	}
 	assert.Equal(t, TestHangupMessage, hangup.BuildMessage())
 }
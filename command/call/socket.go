@@ -0,0 +1,42 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package call
+
+import "strings"
+
+// Socket - Executes the mod_event_socket socket app, connecting the channel to an outbound ESL server
+// Async lets the channel continue while commands are sent, Full gives the socket the same commands as an inbound connection
+type Socket struct {
+	UUID    string
+	Address string // host:port of the outbound ESL server
+	Async   bool
+	Full    bool
+	Sync    bool
+	SyncPri bool
+}
+
+func (s Socket) BuildMessage() string {
+	args := []string{s.Address}
+	if s.Async {
+		args = append(args, "async")
+	}
+	if s.Full {
+		args = append(args, "full")
+	}
+	e := Execute{
+		UUID:    s.UUID,
+		AppName: "socket",
+		AppArgs: strings.Join(args, " "),
+		Sync:    s.Sync,
+		SyncPri: s.SyncPri,
+	}
+	return e.BuildMessage()
+}
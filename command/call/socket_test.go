@@ -0,0 +1,33 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package call
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+var TestSocketMessage = strings.ReplaceAll(`sendmsg none
+Call-Command: execute
+Execute-App-Arg: 127.0.0.1:8084 async full
+Execute-App-Name: socket
+Loops: 1`, "\n", "\r\n")
+
+func TestSocket_BuildMessage(t *testing.T) {
+	socket := Socket{
+		UUID:    "none",
+		Address: "127.0.0.1:8084",
+		Async:   true,
+		Full:    true,
+	}
+	assert.Equal(t, TestSocketMessage, socket.BuildMessage())
+}
@@ -13,14 +13,21 @@ package call
 import (
 	"github.com/percipia/eslgo/command"
 	"net/textproto"
+	"strings"
 )
 
-// Documentation is sparse on this, but it looks like it transfers a call to an application?
+// Application - A dialplan application and its arguments
+type Application struct {
+	Name string
+	Args string
+}
+
+// Transfer - Transfers the channel to an extension made of the applications, executed in order. Sent as the xferext call-command
 type Transfer struct {
-	UUID        string
-	Application string
-	Sync        bool
-	SyncPri     bool
+	UUID         string
+	Applications []Application
+	Sync         bool
+	SyncPri      bool
 }
 
 func (t Transfer) BuildMessage() string {
@@ -31,7 +38,10 @@ func (t Transfer) BuildMessage() string {
 		SyncPri: t.SyncPri,
 	}
 	sendMsg.Headers.Set("call-command", "xferext")
-	sendMsg.Headers.Set("application", t.Application)
+	for _, app := range t.Applications {
+		// FreeSWITCH splits each application header on the first space into the name and arguments
+		sendMsg.Headers.Add("application", strings.TrimSpace(app.Name+" "+app.Args))
+	}
 
 	return sendMsg.BuildMessage()
 }
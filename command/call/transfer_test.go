@@ -11,9 +11,25 @@
 package call
 
 import (
+	"github.com/stretchr/testify/assert"
+	"strings"
 	"testing"
 )
 
+var TestTransferMessage = strings.ReplaceAll(`sendmsg none
+Application: answer
+Application: playback /tmp/welcome.wav
+Application: bridge user/1000
+Call-Command: xferext`, "\n", "\r\n")
+
 func TestTransfer_BuildMessage(t *testing.T) {
-	// No real documentation on this
+	transfer := Transfer{
+		UUID: "none",
+		Applications: []Application{
+			{Name: "answer"},
+			{Name: "playback", Args: "/tmp/welcome.wav"},
+			{Name: "bridge", Args: "user/1000"},
+		},
+	}
+	assert.Equal(t, TestTransferMessage, transfer.BuildMessage())
 }
@@ -10,6 +10,8 @@
  */
 package command
 
+// Connect - The first command on an outbound socket, FreeSWITCH replies with the channel data
+// Whether the socket is async and/or full is decided by the arguments of the socket app, see call.Socket
 type Connect struct{}
 
 func (Connect) BuildMessage() string {
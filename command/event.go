@@ -17,10 +17,12 @@ import (
 	"strings"
 )
 
+// Event - Subscribes to events, or unsubscribes using nixevent when Ignore is true. The format is not sent with nixevent
 type Event struct {
 	Ignore bool
 	Format string
 	Listen []string
+	Custom []string // CUSTOM event subclasses e.g. sofia::register, CUSTOM is added to the list automatically
 }
 
 type MyEvents struct {
@@ -36,17 +38,29 @@ type DivertEvents struct {
 }
 
 type SendEvent struct {
+	UUID    string // Optional channel to deliver the event to instead of firing it globally, sent as the Unique-ID header
 	Name    string
 	Headers textproto.MIMEHeader
 	Body    string
 }
 
 func (e Event) BuildMessage() string {
-	prefix := ""
+	listen := e.Listen
+	if len(e.Custom) > 0 {
+		listen = append([]string(nil), e.Listen...)
+		hasCustom := false
+		for _, name := range listen {
+			hasCustom = hasCustom || strings.EqualFold(name, "CUSTOM")
+		}
+		if !hasCustom {
+			listen = append(listen, "CUSTOM")
+		}
+		listen = append(listen, e.Custom...)
+	}
 	if e.Ignore {
-		prefix = "nix"
+		return fmt.Sprintf("nixevent %s", strings.Join(listen, " "))
 	}
-	return fmt.Sprintf("%sevent %s %s", prefix, e.Format, strings.Join(e.Listen, " "))
+	return fmt.Sprintf("event %s %s", e.Format, strings.Join(listen, " "))
 }
 
 func (m MyEvents) BuildMessage() string {
@@ -69,6 +83,13 @@ func (d DivertEvents) BuildMessage() string {
 }
 
 func (s *SendEvent) BuildMessage() string {
+	if s.Headers == nil {
+		s.Headers = make(textproto.MIMEHeader)
+	}
+	if len(s.UUID) > 0 {
+		s.Headers.Set("Unique-ID", s.UUID)
+	}
+
 	// Ensure the correct content length is set in the header
 	if len(s.Body) > 0 {
 		s.Headers.Set("Content-Length", strconv.Itoa(len(s.Body)))
@@ -21,6 +21,13 @@ MWI-Message-Account: 7100@192.168.1.1
 MWI-Messages-Waiting: yes
 MWI-Voice-Message: 5/5 (1/1)`, "\n", "\r\n")
 
+var TestSendEventUUIDMessage = strings.ReplaceAll(`sendevent SEND_INFO
+Content-Length: 5
+Content-Type: text/plain
+Unique-Id: 7b0a2dc4-1f2c-4c7c-9e9b-8b35f0f1f0a1
+
+hello`, "\n", "\r\n")
+
 func TestDisableEvents_BuildMessage(t *testing.T) {
 	assert.Equal(t, "noevents", DisableEvents{}.BuildMessage())
 }
@@ -35,13 +42,30 @@ func TestEvent_BuildMessage(t *testing.T) {
 		Format: "plain",
 		Listen: []string{"MESSAGE_QUERY"},
 	}.BuildMessage())
-	assert.Equal(t, "nixevent plain MESSAGE_QUERY", Event{
+	assert.Equal(t, "nixevent MESSAGE_QUERY", Event{
 		Ignore: true,
 		Format: "plain",
 		Listen: []string{"MESSAGE_QUERY"},
 	}.BuildMessage())
 }
 
+func TestEvent_BuildMessage_Custom(t *testing.T) {
+	assert.Equal(t, "event json CHANNEL_ANSWER CUSTOM sofia::register sofia::unregister", Event{
+		Format: "json",
+		Listen: []string{"CHANNEL_ANSWER"},
+		Custom: []string{"sofia::register", "sofia::unregister"},
+	}.BuildMessage())
+	assert.Equal(t, "event plain CUSTOM conference::maintenance", Event{
+		Format: "plain",
+		Listen: []string{"CUSTOM"},
+		Custom: []string{"conference::maintenance"},
+	}.BuildMessage())
+	assert.Equal(t, "nixevent CUSTOM sofia::register", Event{
+		Ignore: true,
+		Custom: []string{"sofia::register"},
+	}.BuildMessage())
+}
+
 func TestMyEvents_BuildMessage(t *testing.T) {
 	assert.Equal(t, "myevents plain none", MyEvents{Format: "plain", UUID: "none"}.BuildMessage())
 }
@@ -57,3 +81,13 @@ func TestSendEvent_BuildMessage(t *testing.T) {
 	}
 	assert.Equal(t, TestSendEventMessage, sendEvent.BuildMessage())
 }
+
+func TestSendEvent_BuildMessage_UUID(t *testing.T) {
+	sendEvent := SendEvent{
+		UUID:    "7b0a2dc4-1f2c-4c7c-9e9b-8b35f0f1f0a1",
+		Name:    "SEND_INFO",
+		Headers: map[string][]string{"Content-Type": {"text/plain"}},
+		Body:    "hello",
+	}
+	assert.Equal(t, TestSendEventUUIDMessage, sendEvent.BuildMessage())
+}
@@ -0,0 +1,22 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package command
+
+import "fmt"
+
+// GetVar - Gets a variable of the channel the outbound socket is connected to, the value is in the reply text
+type GetVar struct {
+	Name string
+}
+
+func (g GetVar) BuildMessage() string {
+	return fmt.Sprintf("getvar %s", g.Name)
+}
@@ -17,13 +17,31 @@ import (
 
 type Linger struct {
 	Enabled bool
+	// Deprecated: Seconds is a plain number of seconds stored in a time.Duration, use Timeout instead
 	Seconds time.Duration
+	Timeout time.Duration // How long FreeSWITCH keeps the socket open after hangup, sent in whole seconds and takes precedence over Seconds
+}
+
+// Delay - Returns how long FreeSWITCH keeps the socket open after hangup, 0 if it lingers until the socket is closed
+func (l Linger) Delay() time.Duration {
+	if l.Timeout > 0 {
+		return l.Timeout.Truncate(time.Second)
+	}
+	return l.Seconds * time.Second
+}
+
+// Validate - Returns an error if the Timeout is too short to be sent in whole seconds
+func (l Linger) Validate() error {
+	if l.Enabled && l.Timeout > 0 && l.Timeout < time.Second {
+		return fmt.Errorf("linger timeout %s is shorter than a second", l.Timeout)
+	}
+	return nil
 }
 
 func (l Linger) BuildMessage() string {
 	if l.Enabled {
-		if l.Seconds > 0 {
-			return fmt.Sprintf("linger %d", l.Seconds)
+		if delay := l.Delay(); delay > 0 {
+			return fmt.Sprintf("linger %d", int(delay/time.Second))
 		}
 		return "linger"
 	}
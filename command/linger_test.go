@@ -13,6 +13,7 @@ package command
 import (
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestNoLinger_BuildMessage(t *testing.T) {
@@ -21,4 +22,15 @@ func TestNoLinger_BuildMessage(t *testing.T) {
 
 func TestLinger_BuildMessage(t *testing.T) {
 	assert.Equal(t, "linger", Linger{Enabled: true}.BuildMessage())
+	assert.Equal(t, "linger 10", Linger{Enabled: true, Timeout: 10 * time.Second}.BuildMessage())
+	assert.Equal(t, "linger 1", Linger{Enabled: true, Timeout: 1500 * time.Millisecond}.BuildMessage())
+	// Seconds keeps its old meaning of a plain number of seconds
+	assert.Equal(t, "linger 10", Linger{Enabled: true, Seconds: 10}.BuildMessage())
+	assert.Equal(t, 10*time.Second, Linger{Enabled: true, Seconds: 10}.Delay())
+}
+
+func TestLinger_Validate(t *testing.T) {
+	assert.Nil(t, Linger{Enabled: true, Timeout: time.Second}.Validate())
+	assert.Nil(t, Linger{Enabled: true, Seconds: 10}.Validate())
+	assert.Error(t, Linger{Enabled: true, Timeout: 500 * time.Millisecond}.Validate())
 }
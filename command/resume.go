@@ -0,0 +1,18 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package command
+
+// Resume - Resumes dialplan execution when the outbound socket connection is closed, instead of hanging up the channel
+type Resume struct{}
+
+func (Resume) BuildMessage() string {
+	return "resume"
+}
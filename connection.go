@@ -14,11 +14,16 @@ import (
 	"bufio"
 	"context"
 	"errors"
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/percipia/eslgo/command"
+	"log/slog"
 	"net"
 	"net/textproto"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,25 +31,49 @@ type Conn struct {
 	conn              net.Conn
 	reader            *bufio.Reader
 	header            *textproto.Reader
-	writeLock         sync.Mutex
+	writeLock         chan struct{} // Held while a command is sent and its reply awaited, a channel so waiting for it honors the context
 	runningContext    context.Context
 	stopFunc          func()
 	responseChannels  map[string]chan *RawResponse
 	responseChanMutex sync.RWMutex
 	eventListenerLock sync.RWMutex
 	eventListeners    map[string]map[string]EventListener
+	orderedListeners  map[string]map[string]EventListener
+	logListenerLock   sync.RWMutex
+	logListeners      map[string]LogListener
 	outbound          bool
-	logger            Logger
+	logger            atomic.Pointer[slog.Logger]
 	exitTimeout       time.Duration
 	closeOnce         sync.Once
 	closeDelay        time.Duration
+	onPanic           PanicHandler
+	metrics           Metrics
+	tracer            Tracer
+	unhandledMessage  func(response *RawResponse)
+	recorder          *TrafficRecorder
+	lastReceived      atomic.Int64
+	filterLock        sync.Mutex
+	filters           []command.Filter
+	heartbeatLiveness bool // Set before Dial returns, HEARTBEAT events must keep passing the filters
+	heartbeatFiltered bool // Only used with the write lock held, true when keepHeartbeatFilter added the heartbeat filter
 }
 
+// PanicHandler - Called with the recovered value and stack trace when eslgo recovers a panic from user code
+type PanicHandler func(recovered interface{}, stack []byte)
+
 // Options - Generic options for an ESL connection, either inbound or outbound
 type Options struct {
 	Context     context.Context // This specifies the base running context for the connection. If this context expires all connections will be terminated.
 	Logger      Logger          // This specifies the logger to be used for any library internal messages. Can be set to nil to suppress everything.
+	LogHandler  slog.Handler    // An optional structured log handler. When set it is used instead of Logger and receives connection attributes such as remote_addr and direction.
 	ExitTimeout time.Duration   // How long should we wait for FreeSWITCH to respond to our "exit" command. 5 seconds is a sane default.
+	OnPanic     PanicHandler    // An optional function to be called when a panic is recovered from an event listener or outbound handler. The panic is always logged.
+	Metrics     Metrics         // An optional hook to report runtime metrics about commands, events and connections. See the metrics/prometheus package.
+	Tracer      Tracer          // An optional hook to create spans for commands and outbound call handling. See the tracing/otel package.
+	// An optional function called with messages of a Content-Type eslgo does not handle. Called from the receive loop, so it must not block. Unhandled messages are logged when not set.
+	UnhandledMessage func(response *RawResponse)
+	// An optional recorder writing all traffic of the connection to a file, to replay it later with a Replayer.
+	Recorder *TrafficRecorder
 }
 
 // DefaultOptions - The default options used for creating the connection
@@ -60,9 +89,13 @@ func newConnection(c net.Conn, outbound bool, opts Options) *Conn {
 	reader := bufio.NewReader(c)
 	header := textproto.NewReader(reader)
 
-	// If logger is nil, do not actually output anything
-	if opts.Logger == nil {
-		opts.Logger = NilLogger{}
+	// If metrics is nil, do not actually report anything
+	if opts.Metrics == nil {
+		opts.Metrics = NilMetrics{}
+	}
+	// If tracer is nil, do not actually trace anything
+	if opts.Tracer == nil {
+		opts.Tracer = NilTracer{}
 	}
 
 	runningContext, stop := context.WithCancel(opts.Context)
@@ -77,31 +110,92 @@ func newConnection(c net.Conn, outbound bool, opts Options) *Conn {
 			TypeEventPlain:  make(chan *RawResponse),
 			TypeEventXML:    make(chan *RawResponse),
 			TypeEventJSON:   make(chan *RawResponse),
+			TypeLogData:     make(chan *RawResponse),
 			TypeAuthRequest: make(chan *RawResponse, 1), // Buffered to ensure we do not lose the initial auth request before we are setup to respond
 			TypeDisconnect:  make(chan *RawResponse),
+			// Buffered since FreeSWITCH closes the connection right after rejecting us
+			TypeRudeRejection: make(chan *RawResponse, 1),
 		},
-		runningContext: runningContext,
-		stopFunc:       stop,
-		eventListeners: make(map[string]map[string]EventListener),
-		outbound:       outbound,
-		logger:         opts.Logger,
-		exitTimeout:    opts.ExitTimeout,
+		writeLock:        make(chan struct{}, 1),
+		runningContext:   runningContext,
+		stopFunc:         stop,
+		eventListeners:   make(map[string]map[string]EventListener),
+		orderedListeners: make(map[string]map[string]EventListener),
+		logListeners:     make(map[string]LogListener),
+		outbound:         outbound,
+		exitTimeout:      opts.ExitTimeout,
+		onPanic:          opts.OnPanic,
+		metrics:          opts.Metrics,
+		tracer:           opts.Tracer,
+		unhandledMessage: opts.UnhandledMessage,
+		recorder:         opts.Recorder,
 	}
+	instance.logger.Store(opts.newLogger().With(
+		slog.String(LogKeyRemoteAddr, c.RemoteAddr().String()),
+		slog.String(LogKeyDirection, instance.direction()),
+	))
+	instance.metrics.ConnectionOpened(instance.direction())
 	go instance.receiveLoop()
 	go instance.eventLoop()
+	go instance.logLoop()
 	return instance
 }
 
+// newLogger - Builds the structured logger for library internal messages. If neither LogHandler nor Logger are set nothing is output
+func (opts Options) newLogger() *slog.Logger {
+	if opts.LogHandler != nil {
+		return slog.New(opts.LogHandler)
+	}
+	return slog.New(NewLoggerHandler(opts.Logger))
+}
+
+// direction - Returns "outbound" for connections made to us by FreeSWITCH and "inbound" for connections we dialed
+func (c *Conn) direction() string {
+	if c.outbound {
+		return "outbound"
+	}
+	return "inbound"
+}
+
+// log - Returns the structured logger for this connection with the connection attributes attached
+func (c *Conn) log() *slog.Logger {
+	return c.logger.Load()
+}
+
+// withLogAttrs - Attaches additional attributes to every log from this connection, for example the channel UUID once it is known
+func (c *Conn) withLogAttrs(args ...interface{}) {
+	c.logger.Store(c.log().With(args...))
+}
+
+// commandName - Returns the ESL command name from the built message, e.g. "api" or "sendmsg"
+func commandName(message string) string {
+	if end := strings.IndexAny(message, " \r\n"); end >= 0 {
+		return message[:end]
+	}
+	return message
+}
+
 // RegisterEventListener - Registers a new event listener for the specified channel UUID(or EventListenAll). Returns the registered listener ID used to remove it.
+// Every event is passed to the listener in a new goroutine, so events may be handled out of order
 func (c *Conn) RegisterEventListener(channelUUID string, listener EventListener) string {
+	return c.registerEventListener(c.eventListeners, channelUUID, listener)
+}
+
+// RegisterOrderedEventListener - Registers an event listener that is called from the event loop, so it receives events in the order FreeSWITCH sent them.
+// It is called before the listeners registered with RegisterEventListener, no other events are processed while it runs so it must return quickly. Returns the registered listener ID used to remove it with RemoveEventListener.
+func (c *Conn) RegisterOrderedEventListener(channelUUID string, listener EventListener) string {
+	return c.registerEventListener(c.orderedListeners, channelUUID, listener)
+}
+
+func (c *Conn) registerEventListener(registered map[string]map[string]EventListener, channelUUID string, listener EventListener) string {
 	c.eventListenerLock.Lock()
 	defer c.eventListenerLock.Unlock()
 
 	id := uuid.New().String()
-	if _, ok := c.eventListeners[channelUUID]; ok {
-		c.eventListeners[channelUUID][id] = listener
+	if _, ok := registered[channelUUID]; ok {
+		registered[channelUUID][id] = listener
 	} else {
-		c.eventListeners[channelUUID] = map[string]EventListener{id: listener}
+		registered[channelUUID] = map[string]EventListener{id: listener}
 	}
 	return id
 }
@@ -114,17 +208,29 @@ func (c *Conn) RemoveEventListener(channelUUID string, id string) {
 	if listeners, ok := c.eventListeners[channelUUID]; ok {
 		delete(listeners, id)
 	}
+	if listeners, ok := c.orderedListeners[channelUUID]; ok {
+		delete(listeners, id)
+	}
 }
 
 // SendCommand - Sends the specified ESL command to FreeSWITCH with the provided context. Returns the response data and any errors encountered.
 func (c *Conn) SendCommand(ctx context.Context, cmd command.Command) (*RawResponse, error) {
-	c.writeLock.Lock()
-	defer c.writeLock.Unlock()
+	linger, isLinger := cmd.(command.Linger)
+	if isLinger {
+		if err := linger.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.lockWrite(ctx); err != nil {
+		return nil, err
+	}
+	defer c.unlockWrite()
 
-	if linger, ok := cmd.(command.Linger); ok {
+	if isLinger {
 		if linger.Enabled {
-			if linger.Seconds > 0 {
-				c.closeDelay = linger.Seconds
+			if delay := linger.Delay(); delay > 0 {
+				c.closeDelay = delay
 			} else {
 				c.closeDelay = -1
 			}
@@ -133,13 +239,70 @@ func (c *Conn) SendCommand(ctx context.Context, cmd command.Command) (*RawRespon
 		}
 	}
 
+	message := cmd.BuildMessage()
+	name := commandName(message)
+	ctx, span := c.tracer.Start(ctx, "ESL "+name, SpanKindClient)
+	defer span.End()
+	span.SetAttribute(TraceKeyCommand, name)
+	if channelUUID := commandChannelUUID(message); len(channelUUID) > 0 {
+		span.SetAttribute(TraceKeyChannelUUID, channelUUID)
+	}
+
+	sent := time.Now()
+	response, err := c.sendMessage(ctx, name, message)
+	if err != nil {
+		span.RecordError(err)
+		return nil, err
+	}
+
+	latency := time.Since(sent)
+	reply := replyStatus(response)
+	isError := strings.HasPrefix(reply, "-ERR")
+	c.metrics.CommandReplied(name, latency, isError)
+	span.SetAttribute(TraceKeyReply, reply)
+	span.SetAttribute(TraceKeyLatency, latency.Milliseconds())
+	if isError {
+		span.RecordError(errors.New(reply))
+	} else if filter, ok := cmd.(command.Filter); ok {
+		c.trackFilter(filter)
+		c.keepHeartbeatFilter(ctx)
+	}
+	return response, nil
+}
+
+// lockWrite - Waits for the previous command to be answered, giving up when the context is done so a command stuck on a dead link cannot block every later one
+func (c *Conn) lockWrite(ctx context.Context) error {
+	select {
+	case c.writeLock <- struct{}{}:
+		return nil
+	case <-c.runningContext.Done():
+		return ErrConnectionClosed
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", errWriteBusy, ctx.Err())
+	}
+}
+
+func (c *Conn) unlockWrite() {
+	<-c.writeLock
+}
+
+// sendMessage - Writes the built message to FreeSWITCH and waits for the reply. Must be called with the write lock held
+func (c *Conn) sendMessage(ctx context.Context, name, message string) (*RawResponse, error) {
+	if c.runningContext.Err() != nil {
+		return nil, ErrConnectionClosed
+	}
 	if deadline, ok := ctx.Deadline(); ok {
 		_ = c.conn.SetWriteDeadline(deadline)
 	}
-	_, err := c.conn.Write([]byte(cmd.BuildMessage() + EndOfMessage))
+	if c.recorder != nil {
+		// Recorded before writing, the reply could otherwise be received and recorded ahead of the command
+		c.recorder.sent(message + EndOfMessage)
+	}
+	_, err := c.conn.Write([]byte(message + EndOfMessage))
 	if err != nil {
 		return nil, err
 	}
+	c.metrics.CommandSent(name)
 
 	// Get response
 	c.responseChanMutex.RLock()
@@ -148,20 +311,32 @@ func (c *Conn) SendCommand(ctx context.Context, cmd command.Command) (*RawRespon
 	case response := <-c.responseChannels[TypeReply]:
 		if response == nil {
 			// We only get nil here if the channel is closed
-			return nil, errors.New("connection closed")
+			return nil, ErrConnectionClosed
 		}
 		return response, nil
 	case response := <-c.responseChannels[TypeAPIResponse]:
 		if response == nil {
 			// We only get nil here if the channel is closed
-			return nil, errors.New("connection closed")
+			return nil, ErrConnectionClosed
 		}
 		return response, nil
+	case <-c.runningContext.Done():
+		// Close waits for the response channel lock, so a reply that never comes must not hold it
+		return nil, ErrConnectionClosed
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 }
 
+// replyStatus - Returns the first line of the reply, API responses can have large multi-line bodies
+func replyStatus(response *RawResponse) string {
+	reply := response.GetReply()
+	if end := strings.IndexAny(reply, "\r\n"); end >= 0 {
+		reply = reply[:end]
+	}
+	return strings.TrimSpace(reply)
+}
+
 // ExitAndClose - Attempt to gracefully send FreeSWITCH "exit" over the ESL connection before closing our connection and stopping. Protected by a sync.Once
 func (c *Conn) ExitAndClose() {
 	c.closeOnce.Do(func() {
@@ -190,47 +365,62 @@ func (c *Conn) close() {
 
 	// Close the connection only after we have the response channel lock and we have deleted all response channels to ensure we don't receive on a closed channel
 	_ = c.conn.Close()
+	c.metrics.ConnectionClosed(c.direction())
 }
 
 func (c *Conn) callEventListener(event *Event) {
+	var ordered, listeners []EventListener
 	c.eventListenerLock.RLock()
-	defer c.eventListenerLock.RUnlock()
-
-	// First check if there are any general event listener
-	if listeners, ok := c.eventListeners[EventListenAll]; ok {
-		for _, listener := range listeners {
-			go listener(event)
+	for _, key := range eventListenerKeys(event) {
+		for _, listener := range c.orderedListeners[key] {
+			ordered = append(ordered, listener)
+		}
+		for _, listener := range c.eventListeners[key] {
+			listeners = append(listeners, listener)
 		}
 	}
+	c.eventListenerLock.RUnlock()
 
-	// Next call any listeners for a particular channel
-	if event.HasHeader("Unique-Id") {
-		channelUUID := event.GetHeader("Unique-Id")
-		if listeners, ok := c.eventListeners[channelUUID]; ok {
-			for _, listener := range listeners {
-				go listener(event)
-			}
-		}
+	// Ordered listeners are called first and without the lock so they can register and remove listeners
+	for _, listener := range ordered {
+		c.safeCallListener(listener, event)
 	}
+	for _, listener := range listeners {
+		go c.safeCallListener(listener, event)
+	}
+}
 
-	// Next call any listeners for a particular application
-	if event.HasHeader("Application-UUID") {
-		appUUID := event.GetHeader("Application-UUID")
-		if listeners, ok := c.eventListeners[appUUID]; ok {
-			for _, listener := range listeners {
-				go listener(event)
-			}
+// eventListenerKeys - Returns the keys event listeners can be registered under for this event
+func eventListenerKeys(event *Event) []string {
+	// First any general event listener
+	keys := []string{EventListenAll}
+	// Next any listeners for a particular channel, application, or job
+	for _, header := range []string{"Unique-Id", "Application-UUID", "Job-UUID"} {
+		if event.HasHeader(header) {
+			keys = append(keys, event.GetHeader(header))
 		}
 	}
+	return keys
+}
 
-	// Next call any listeners for a particular job
-	if event.HasHeader("Job-UUID") {
-		jobUUID := event.GetHeader("Job-UUID")
-		if listeners, ok := c.eventListeners[jobUUID]; ok {
-			for _, listener := range listeners {
-				go listener(event)
-			}
-		}
+// safeCallListener - Calls the listener recovering any panic so a misbehaving listener cannot take down the process
+func (c *Conn) safeCallListener(listener EventListener, event *Event) {
+	c.metrics.ListenerQueueChanged(1)
+	defer c.metrics.ListenerQueueChanged(-1)
+	defer c.recoverPanic("event listener", slog.String(LogKeyEvent, event.GetName()), slog.String(LogKeyChannelUUID, event.GetHeader("Unique-Id")))
+	listener(event)
+}
+
+// recoverPanic - Must be deferred directly. Logs any recovered panic with its stack trace and passes it to the OnPanic hook
+func (c *Conn) recoverPanic(source string, attrs ...interface{}) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	stack := debug.Stack()
+	c.log().With(attrs...).Error("Recovered panic", "source", source, "panic", recovered, "stack", string(stack))
+	if c.onPanic != nil {
+		c.onPanic(recovered, stack)
 	}
 }
 
@@ -268,10 +458,11 @@ func (c *Conn) eventLoop() {
 		c.responseChanMutex.RUnlock()
 
 		if err != nil {
-			c.logger.Warn("Error parsing event\n%s\n", err.Error())
+			c.log().Warn("Error parsing event", LogKeyError, err)
 			continue
 		}
 
+		c.metrics.EventReceived(event.GetName())
 		c.callEventListener(event)
 	}
 }
@@ -280,17 +471,25 @@ func (c *Conn) receiveLoop() {
 	for c.runningContext.Err() == nil {
 		err := c.doMessage()
 		if err != nil {
-			c.logger.Warn("Error receiving message: %s\n", err.Error())
+			c.log().Warn("Error receiving message", LogKeyError, err)
 			break
 		}
 	}
 }
 
+// responseChannel - Returns the channel for the Content-Type, nil once the connection has been closed
+func (c *Conn) responseChannel(contentType string) chan *RawResponse {
+	c.responseChanMutex.RLock()
+	defer c.responseChanMutex.RUnlock()
+	return c.responseChannels[contentType]
+}
+
 func (c *Conn) doMessage() error {
 	response, err := c.readResponse()
 	if err != nil {
 		return err
 	}
+	c.markReceived()
 
 	c.responseChanMutex.RLock()
 	defer c.responseChanMutex.RUnlock()
@@ -300,23 +499,36 @@ func (c *Conn) doMessage() error {
 		return errors.New("no response channels")
 	}
 
-	// We have a handler
-	if ok {
-		// Only allow 5 seconds to allow the handler to receive hte message on the channel
-		ctx, cancel := context.WithTimeout(c.runningContext, 5*time.Second)
-		defer cancel()
+	if !ok {
+		// Not fatal, FreeSWITCH can send content types we do not know about
+		c.handleUnknownMessage(response)
+		return nil
+	}
 
-		select {
-		case responseChan <- response:
-		case <-c.runningContext.Done():
-			// Parent connection context has stopped we most likely shutdown in the middle of waiting for a handler to handle the message
-			return c.runningContext.Err()
-		case <-ctx.Done():
-			// Do not return an error since this is not fatal but log since it could be a indication of problems
-			c.logger.Warn("No one to handle response\nIs the connection overloaded or stopping?\n%v\n\n", response)
-		}
-	} else {
-		return errors.New("no response channel for Content-Type: " + response.GetHeader("Content-Type"))
+	// Only allow 5 seconds to allow the handler to receive hte message on the channel
+	ctx, cancel := context.WithTimeout(c.runningContext, 5*time.Second)
+	defer cancel()
+
+	select {
+	case responseChan <- response:
+	case <-c.runningContext.Done():
+		// Parent connection context has stopped we most likely shutdown in the middle of waiting for a handler to handle the message
+		return c.runningContext.Err()
+	case <-ctx.Done():
+		// Do not return an error since this is not fatal but log since it could be a indication of problems
+		c.metrics.ResponseDropped(response.GetHeader("Content-Type"))
+		c.log().Warn("No one to handle response, is the connection overloaded or stopping?", "content_type", response.GetHeader("Content-Type"), "response", response.String())
 	}
 	return nil
 }
+
+// handleUnknownMessage - Passes a message with a Content-Type we have no response channel for to the UnhandledMessage hook, or logs it
+func (c *Conn) handleUnknownMessage(response *RawResponse) {
+	if c.unhandledMessage == nil {
+		c.metrics.ResponseDropped(response.GetHeader("Content-Type"))
+		c.log().Warn("Ignoring message with unknown Content-Type", "content_type", response.GetHeader("Content-Type"), "response", response.String())
+		return
+	}
+	defer c.recoverPanic("unhandled message hook", "content_type", response.GetHeader("Content-Type"))
+	c.unhandledMessage(response)
+}
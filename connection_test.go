@@ -56,3 +56,84 @@ func TestConn_SendCommand(t *testing.T) {
 	assert.Nil(t, err)
 	wait.Wait()
 }
+
+type recordingMetrics struct {
+	NilMetrics
+	mutex   sync.Mutex
+	sent    []string
+	replies []bool
+}
+
+func (m *recordingMetrics) CommandSent(name string) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.sent = append(m.sent, name)
+}
+
+func (m *recordingMetrics) CommandReplied(name string, latency time.Duration, isError bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.replies = append(m.replies, isError)
+}
+
+func TestConn_SendCommand_Metrics(t *testing.T) {
+	server, client := net.Pipe()
+	metrics := &recordingMetrics{}
+	opts := DefaultOptions
+	opts.Metrics = metrics
+	connection := newConnection(client, false, opts)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	serverReader := bufio.NewReader(server)
+	go func() {
+		_, _ = serverReader.ReadString('\r')
+		_, _ = server.Write([]byte("Content-Type: api/response\r\nContent-Length: 21\r\n\r\n-ERR no reply to that"))
+	}()
+
+	response, err := connection.SendCommand(ctx, command.API{Command: "status"})
+	assert.Nil(t, err)
+	assert.False(t, response.IsOk())
+
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	assert.Equal(t, []string{"api"}, metrics.sent)
+	assert.Equal(t, []bool{true}, metrics.replies)
+}
+
+func TestConn_UnhandledMessage(t *testing.T) {
+	server, client := net.Pipe()
+	unhandled := make(chan *RawResponse, 1)
+	opts := DefaultOptions
+	opts.Logger = NilLogger{}
+	opts.UnhandledMessage = func(response *RawResponse) {
+		unhandled <- response
+	}
+	connection := newConnection(client, false, opts)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	serverReader := bufio.NewReader(server)
+	go func() {
+		_, _ = serverReader.ReadString('\r')
+		_, _ = server.Write([]byte("Content-Type: text/something-new\r\nContent-Length: 5\r\n\r\nhello"))
+		_, _ = server.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"))
+	}()
+
+	// The connection must keep working after a message it does not understand
+	response, err := connection.SendCommand(ctx, command.API{Command: "status"})
+	assert.Nil(t, err)
+	assert.True(t, response.IsOk())
+
+	message := <-unhandled
+	assert.Equal(t, "text/something-new", message.GetHeader("Content-Type"))
+	assert.Equal(t, "hello", string(message.Body))
+}
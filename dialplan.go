@@ -0,0 +1,196 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/percipia/eslgo/command/call"
+	"strings"
+)
+
+// inlineDelimiters - Delimiters tried in order when an application argument contains a comma. ':' separates the application from its arguments so it cannot be used
+const inlineDelimiters = ";~!#%&*@|"
+
+// InlineDialplan - Applications executed in order by the FreeSWITCH inline dialplan, e.g. answer,playback:/tmp/welcome.wav
+type InlineDialplan []call.Application
+
+// DialplanExtension - An XML dialplan extension, for example to return from mod_xml_curl or to put in a dialplan file
+type DialplanExtension struct {
+	XMLName    xml.Name            `xml:"extension"`
+	Name       string              `xml:"name,attr"`
+	Continue   bool                `xml:"continue,attr,omitempty"`
+	Conditions []DialplanCondition `xml:"condition"`
+}
+
+// DialplanCondition - A condition of an XML dialplan extension. Actions run when the expression matches the field, anti-actions when it does not
+type DialplanCondition struct {
+	Field       string           `xml:"field,attr,omitempty"`
+	Expression  string           `xml:"expression,attr,omitempty"`
+	Break       string           `xml:"break,attr,omitempty"`
+	Actions     []DialplanAction `xml:"action"`
+	AntiActions []DialplanAction `xml:"anti-action"`
+}
+
+// DialplanAction - An action of an XML dialplan condition
+type DialplanAction struct {
+	Application string `xml:"application,attr"`
+	Data        string `xml:"data,attr,omitempty"`
+	Inline      bool   `xml:"inline,attr,omitempty"`
+}
+
+// NewInlineDialplan - Creates an inline dialplan from pairs of application names and arguments e.g. NewInlineDialplan("answer", "", "playback", "/tmp/welcome.wav")
+// A missing argument for the last application is treated as empty
+func NewInlineDialplan(appsAndArgs ...string) InlineDialplan {
+	var dialplan InlineDialplan
+	for i := 0; i < len(appsAndArgs); i += 2 {
+		app := call.Application{Name: appsAndArgs[i]}
+		if i+1 < len(appsAndArgs) {
+			app.Args = appsAndArgs[i+1]
+		}
+		dialplan = append(dialplan, app)
+	}
+	return dialplan
+}
+
+// Build - Builds the inline dialplan string. If any argument contains a comma the delimiter is switched using the m:X: prefix e.g. m:;:set:codecs=PCMU,PCMA;park
+// Returns ErrInlineDelimiter when the arguments contain a comma and every other delimiter, the applications could not be told apart
+func (d InlineDialplan) Build() (string, error) {
+	delimiter, ok := d.delimiter()
+	if !ok {
+		return "", ErrInlineDelimiter
+	}
+	var builder strings.Builder
+	if delimiter != ',' {
+		builder.WriteString("m:")
+		builder.WriteRune(delimiter)
+		builder.WriteString(":")
+	}
+	for i, app := range d {
+		if i > 0 {
+			builder.WriteRune(delimiter)
+		}
+		builder.WriteString(app.Name)
+		if len(app.Args) > 0 {
+			builder.WriteString(":")
+			builder.WriteString(app.Args)
+		}
+	}
+	return builder.String(), nil
+}
+
+// String - The inline dialplan string built by Build, empty when it cannot be built
+func (d InlineDialplan) String() string {
+	dialplan, _ := d.Build()
+	return dialplan
+}
+
+// Destination - The inline dialplan as the destination of transfer, execute_extension or originate, quoted so it is kept as one argument, followed by "inline"
+func (d InlineDialplan) Destination() (string, error) {
+	dialplan, err := d.Build()
+	if err != nil {
+		return "", err
+	}
+	return quoteVar(dialplan) + " inline", nil
+}
+
+// Leg - The inline dialplan as the bLeg of OriginateCall
+func (d InlineDialplan) Leg() (Leg, error) {
+	destination, err := d.Destination()
+	return Leg{CallURL: destination}, err
+}
+
+// Extension - The inline dialplan as an XML extension with a single unconditional condition
+func (d InlineDialplan) Extension(name string) DialplanExtension {
+	condition := DialplanCondition{}
+	for _, app := range d {
+		condition.Actions = append(condition.Actions, DialplanAction{
+			Application: app.Name,
+			Data:        app.Args,
+		})
+	}
+	return DialplanExtension{
+		Name:       name,
+		Conditions: []DialplanCondition{condition},
+	}
+}
+
+// delimiter - Returns the first delimiter no argument contains, false if there is none
+func (d InlineDialplan) delimiter() (rune, bool) {
+	contains := func(delimiter rune) bool {
+		for _, app := range d {
+			if strings.ContainsRune(app.Args, delimiter) {
+				return true
+			}
+		}
+		return false
+	}
+	if !contains(',') {
+		return ',', true
+	}
+	for _, delimiter := range inlineDelimiters {
+		if !contains(delimiter) {
+			return delimiter, true
+		}
+	}
+	return 0, false
+}
+
+// ApplicationDestination - The single application originate destination, e.g. &playback(/tmp/welcome.wav)
+func ApplicationDestination(app, args string) string {
+	return fmt.Sprintf("&%s(%s)", app, args)
+}
+
+// String - Builds the XML of the extension, attribute values are escaped
+func (e DialplanExtension) String() string {
+	data, err := xml.MarshalIndent(e, "", "  ")
+	if err != nil {
+		// Only happens with invalid UTF-8 names, which FreeSWITCH would reject as well
+		return ""
+	}
+	return string(data)
+}
+
+// ExecuteExtension - Executes the mod_dptools execute_extension app, running the extension and returning to the current dialplan afterwards
+func (c *Conn) ExecuteExtension(ctx context.Context, uuid, extension, dialplan, dialplanContext string) error {
+	args := extension
+	if len(dialplan) > 0 {
+		args += " " + dialplan
+		if len(dialplanContext) > 0 {
+			args += " " + dialplanContext
+		}
+	}
+	_, err := c.SendCommandChecked(ctx, &call.Execute{
+		UUID:    uuid,
+		AppName: "execute_extension",
+		AppArgs: args,
+	})
+	return err
+}
+
+// ExecuteInline - Executes the inline dialplan on the channel using execute_extension
+func (c *Conn) ExecuteInline(ctx context.Context, uuid string, dialplan InlineDialplan) error {
+	destination, err := dialplan.Destination()
+	if err != nil {
+		return err
+	}
+	return c.ExecuteExtension(ctx, uuid, destination, "", "")
+}
+
+// TransferInline - Transfers the channel to the inline dialplan using uuid_transfer
+func (c *Conn) TransferInline(ctx context.Context, uuid string, leg TransferLeg, dialplan InlineDialplan) error {
+	built, err := dialplan.Build()
+	if err != nil {
+		return err
+	}
+	return c.TransferCall(ctx, uuid, leg, quoteVar(built), "inline", "")
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestInlineDialplan_String(t *testing.T) {
+	dialplan := NewInlineDialplan("answer", "", "playback", "/tmp/welcome.wav", "park")
+	assert.Equal(t, "answer,playback:/tmp/welcome.wav,park", dialplan.String())
+	destination, err := dialplan.Destination()
+	assert.Nil(t, err)
+	assert.Equal(t, "'answer,playback:/tmp/welcome.wav,park' inline", destination)
+
+	// Commas in arguments switch the delimiter
+	dialplan = NewInlineDialplan("set", "absolute_codec_string=PCMU,PCMA", "playback", "say:it's; done")
+	assert.Equal(t, "m:~:set:absolute_codec_string=PCMU,PCMA~playback:say:it's; done", dialplan.String())
+	destination, err = dialplan.Destination()
+	assert.Nil(t, err)
+	assert.Equal(t, `'m:~:set:absolute_codec_string=PCMU,PCMA~playback:say:it\'s; done' inline`, destination)
+	leg, err := dialplan.Leg()
+	assert.Nil(t, err)
+	assert.Equal(t, Leg{CallURL: destination}, leg)
+
+	// Every delimiter is used by the arguments, any choice would split the applications in the wrong places
+	dialplan = NewInlineDialplan("set", "chars=,"+inlineDelimiters, "park", "")
+	_, err = dialplan.Build()
+	assert.ErrorIs(t, err, ErrInlineDelimiter)
+	assert.Empty(t, dialplan.String())
+	_, err = dialplan.Leg()
+	assert.ErrorIs(t, err, ErrInlineDelimiter)
+
+	assert.Equal(t, "&playback(/tmp/welcome.wav)", ApplicationDestination("playback", "/tmp/welcome.wav"))
+}
+
+func TestDialplanExtension_String(t *testing.T) {
+	extension := NewInlineDialplan("answer", "", "playback", `/tmp/"welcome".wav`).Extension("welcome")
+	assert.Equal(t, `<extension name="welcome">
+  <condition>
+    <action application="answer"></action>
+    <action application="playback" data="/tmp/&#34;welcome&#34;.wav"></action>
+  </condition>
+</extension>`, extension.String())
+
+	extension = DialplanExtension{
+		Name:     "business_hours",
+		Continue: true,
+		Conditions: []DialplanCondition{{
+			Field:       "wday",
+			Expression:  "^[2-6]$",
+			Actions:     []DialplanAction{{Application: "set", Data: "open=true", Inline: true}},
+			AntiActions: []DialplanAction{{Application: "set", Data: "open=false", Inline: true}},
+		}},
+	}
+	assert.Equal(t, `<extension name="business_hours" continue="true">
+  <condition field="wday" expression="^[2-6]$">
+    <action application="set" data="open=true" inline="true"></action>
+    <anti-action application="set" data="open=false" inline="true"></anti-action>
+  </condition>
+</extension>`, extension.String())
+}
+
+func TestConn_TransferInline(t *testing.T) {
+	conn, commands := dialTestServer(t, func(command string) string {
+		return testAPIResponse("+OK\n")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, conn.TransferInline(ctx, "channel", TransferBLeg, NewInlineDialplan("playback", "/tmp/moh.wav", "hangup")))
+	assert.ErrorIs(t, conn.TransferInline(ctx, "channel", TransferBLeg, NewInlineDialplan("set", "chars=,"+inlineDelimiters)), ErrInlineDelimiter)
+	assert.Equal(t, []string{"api uuid_transfer channel -bleg 'playback:/tmp/moh.wav,hangup' inline"}, commands())
+}
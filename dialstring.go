@@ -0,0 +1,230 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DialString - A full dial string for originate and bridge. Variables are written sorted by name so the same DialString always produces the same string
+type DialString struct {
+	Variables map[string]string // Enterprise variables applied to every branch, contained in <>
+	Branches  []DialBranch      // Branches are dialed simultaneously using the enterprise separator ":_:"
+}
+
+// DialBranch - A single enterprise branch of a dial string
+type DialBranch struct {
+	Variables map[string]string // Variables applied to every leg in the branch, contained in {}
+	Groups    []LegGroup        // Groups are tried in order("|") until one answers, legs in a group are dialed simultaneously(",")
+}
+
+// NewDialString - Creates a DialString with a single branch trying each LegGroup in order
+func NewDialString(vars map[string]string, groups ...LegGroup) DialString {
+	return DialString{
+		Branches: []DialBranch{{
+			Variables: vars,
+			Groups:    groups,
+		}},
+	}
+}
+
+// GatewayLeg - A leg dialing the destination through a sofia gateway, sofia/gateway/<gateway>/<destination>
+func GatewayLeg(gateway, destination string) Leg {
+	return Leg{CallURL: fmt.Sprintf("sofia/gateway/%s/%s", gateway, destination)}
+}
+
+// UserLeg - A leg dialing a directory user, user/<user>@<domain>. The domain is optional
+func UserLeg(user, domain string) Leg {
+	if len(domain) == 0 {
+		return Leg{CallURL: "user/" + user}
+	}
+	return Leg{CallURL: fmt.Sprintf("user/%s@%s", user, domain)}
+}
+
+// LoopbackLeg - A leg running the extension through the dialplan, loopback/<extension>/<context>. The context is optional
+func LoopbackLeg(extension, context string) Leg {
+	if len(context) == 0 {
+		return Leg{CallURL: "loopback/" + extension}
+	}
+	return Leg{CallURL: fmt.Sprintf("loopback/%s/%s", extension, context)}
+}
+
+// SofiaLeg - A leg dialing the SIP URI through a sofia profile, sofia/<profile>/<uri>
+func SofiaLeg(profile, uri string) Leg {
+	return Leg{CallURL: fmt.Sprintf("sofia/%s/%s", profile, uri)}
+}
+
+// String - Builds the dial string
+func (d DialString) String() string {
+	var builder strings.Builder
+	builder.WriteString(BuildVars("<%s>", d.Variables))
+	for i, branch := range d.Branches {
+		if i > 0 {
+			builder.WriteString(":_:")
+		}
+		builder.WriteString(branch.String())
+	}
+	return builder.String()
+}
+
+// String - Builds the dial string for the branch
+func (b DialBranch) String() string {
+	var builder strings.Builder
+	builder.WriteString(BuildVars("{%s}", b.Variables))
+	for i, group := range b.Groups {
+		if i > 0 {
+			builder.WriteString("|")
+		}
+		for j, leg := range group {
+			if j > 0 {
+				builder.WriteString(",")
+			}
+			builder.WriteString(leg.String())
+		}
+	}
+	return builder.String()
+}
+
+// ParseDialString - Parses a dial string back into a DialString
+func ParseDialString(dialString string) (DialString, error) {
+	var d DialString
+	var err error
+	rest := strings.TrimSpace(dialString)
+	if len(rest) == 0 {
+		return d, errors.New("empty dial string")
+	}
+
+	d.Variables, rest, err = parseVarBlock(rest, '<', '>')
+	if err != nil {
+		return d, err
+	}
+
+	for _, branchString := range splitDialString(rest, ":_:") {
+		branch, err := parseDialBranch(strings.TrimSpace(branchString))
+		if err != nil {
+			return d, err
+		}
+		d.Branches = append(d.Branches, branch)
+	}
+	return d, nil
+}
+
+func parseDialBranch(branchString string) (DialBranch, error) {
+	var branch DialBranch
+	var err error
+	branch.Variables, branchString, err = parseVarBlock(branchString, '{', '}')
+	if err != nil {
+		return branch, err
+	}
+
+	for _, groupString := range splitDialString(branchString, "|") {
+		var group LegGroup
+		for _, legString := range splitDialString(groupString, ",") {
+			var leg Leg
+			leg.LegVariables, leg.CallURL, err = parseVarBlock(strings.TrimSpace(legString), '[', ']')
+			if err != nil {
+				return branch, err
+			}
+			if len(leg.CallURL) == 0 {
+				return branch, fmt.Errorf("empty leg in dial string %q", branchString)
+			}
+			group = append(group, leg)
+		}
+		branch.Groups = append(branch.Groups, group)
+	}
+	return branch, nil
+}
+
+// parseVarBlock - Parses the variable block at the start of s if it starts with open, returning the variables and the remainder of s
+func parseVarBlock(s string, open, close byte) (map[string]string, string, error) {
+	if len(s) == 0 || s[0] != open {
+		return nil, s, nil
+	}
+	end := findDialStringClose(s, open, close)
+	if end < 0 {
+		return nil, s, fmt.Errorf("unterminated %c in dial string %q", open, s)
+	}
+	vars, err := parseVars(s[1:end])
+	return vars, s[end+1:], err
+}
+
+// parseVars - Parses the content of a variable block, handling the ^^ delimiter syntax, quotes and escapes
+func parseVars(content string) (map[string]string, error) {
+	delimiter := ","
+	if strings.HasPrefix(content, "^^") && len(content) > 2 {
+		delimiter = content[2:3]
+		content = content[3:]
+	}
+	if len(content) == 0 {
+		return nil, nil
+	}
+
+	vars := make(map[string]string)
+	for _, variable := range splitDialString(content, delimiter) {
+		key, value, ok := strings.Cut(variable, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid variable %q in dial string", variable)
+		}
+		vars[strings.TrimSpace(key)] = unescapeVar(value)
+	}
+	return vars, nil
+}
+
+// findDialStringClose - Finds the index of the close character matching the open character at the start of s, ignoring quoted and escaped characters
+func findDialStringClose(s string, open, close byte) int {
+	depth := 0
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '\'':
+			quoted = !quoted
+		case quoted:
+		case s[i] == open:
+			depth++
+		case s[i] == close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitDialString - Splits s on the separator when it is not inside a variable block, quotes or escaped
+func splitDialString(s, separator string) []string {
+	var parts []string
+	depth := 0
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case s[i] == '\'':
+			quoted = !quoted
+		case quoted:
+		case strings.IndexByte("[{<", s[i]) >= 0:
+			depth++
+		case strings.IndexByte("]}>", s[i]) >= 0 && depth > 0:
+			depth--
+		case depth == 0 && strings.HasPrefix(s[i:], separator):
+			parts = append(parts, s[start:i])
+			i += len(separator) - 1
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
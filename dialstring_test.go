@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDialString_String(t *testing.T) {
+	primary := UserLeg("1000", "example.com")
+	primary.LegVariables = map[string]string{"leg_timeout": "20"}
+	dialString := DialString{
+		Variables: map[string]string{"ignore_early_media": "true"},
+		Branches: []DialBranch{
+			{
+				Variables: map[string]string{
+					"origination_caller_id_name": "John Doe",
+					"absolute_codec_string":      "PCMU,PCMA",
+				},
+				Groups: []LegGroup{
+					{primary, LoopbackLeg("1001", "default")},
+					{GatewayLeg("backup", "15555550100")},
+				},
+			},
+			{Groups: []LegGroup{{SofiaLeg("internal", "1002@10.0.0.1")}}},
+		},
+	}
+
+	assert.Equal(t, "<ignore_early_media=true>"+
+		"{^^:absolute_codec_string=PCMU,PCMA:origination_caller_id_name='John Doe'}"+
+		"[leg_timeout=20]user/1000@example.com,loopback/1001/default|sofia/gateway/backup/15555550100"+
+		":_:sofia/internal/1002@10.0.0.1", dialString.String())
+}
+
+func TestBuildVars_Escaping(t *testing.T) {
+	assert.Equal(t, `{a=1,b='it\'s',c='C:\\dir'}`, BuildVars("{%s}", map[string]string{
+		"c": `C:\dir`,
+		"b": "it's",
+		"a": "1",
+	}))
+	// Both , and : are used so the next delimiter is picked
+	assert.Equal(t, "[^^;a=1,2;b=sip:1000]", BuildVars("[%s]", map[string]string{
+		"a": "1,2",
+		"b": "sip:1000",
+	}))
+	// Every delimiter is used so the commas are kept inside quotes instead
+	exhausted := map[string]string{
+		"a": "1,2" + varDelimiters,
+		"b": "3",
+	}
+	vars := BuildVars("{%s}", exhausted)
+	assert.Equal(t, "{a='1,2:;~!#%&*@',b=3}", vars)
+	parsed, err := parseVars(vars[1 : len(vars)-1])
+	assert.Nil(t, err)
+	assert.Equal(t, exhausted, parsed)
+}
+
+func TestParseDialString(t *testing.T) {
+	tests := []string{
+		"user/1000",
+		"{a=1,b='John Doe'}user/1000,user/1001|sofia/gateway/gw/100",
+		"<x=y>{^^:codecs=PCMU,PCMA:c=d}[leg_timeout=10]user/1000:_:[^^;e=1,2;f=3]loopback/9999/default",
+		`{a='it\'s',b='C:\\dir'}sofia/internal/1000@10.0.0.1`,
+	}
+	for _, test := range tests {
+		dialString, err := ParseDialString(test)
+		if assert.Nil(t, err, test) {
+			reparsed, err := ParseDialString(dialString.String())
+			assert.Nil(t, err, test)
+			assert.Equal(t, dialString, reparsed, test)
+		}
+	}
+
+	dialString, err := ParseDialString("<x=y>{^^:codecs=PCMU,PCMA:name='John Doe'}[leg_timeout=10]user/1000,user/1001|user/1002:_:loopback/9999")
+	assert.Nil(t, err)
+	assert.Equal(t, DialString{
+		Variables: map[string]string{"x": "y"},
+		Branches: []DialBranch{
+			{
+				Variables: map[string]string{"codecs": "PCMU,PCMA", "name": "John Doe"},
+				Groups: []LegGroup{
+					{{CallURL: "user/1000", LegVariables: map[string]string{"leg_timeout": "10"}}, {CallURL: "user/1001"}},
+					{{CallURL: "user/1002"}},
+				},
+			},
+			{Groups: []LegGroup{{{CallURL: "loopback/9999"}}}},
+		},
+	}, dialString)
+
+	_, err = ParseDialString("{a=1user/1000")
+	assert.NotNil(t, err)
+	_, err = ParseDialString("[novalue]user/1000")
+	assert.NotNil(t, err)
+	_, err = ParseDialString("user/1000,")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"github.com/percipia/eslgo/command"
+	"github.com/percipia/eslgo/command/call"
+	"strings"
+)
+
+var (
+	// ErrConnectionClosed - The connection was closed before a reply was received
+	ErrConnectionClosed = errors.New("connection closed")
+	// ErrACLRejected - FreeSWITCH rejected the connection because our address is not allowed by the event_socket apply-inbound-acl
+	ErrACLRejected = errors.New("connection rejected by FreeSWITCH ACL")
+	// ErrAuthFailed - FreeSWITCH rejected our auth or userauth command
+	ErrAuthFailed = errors.New("authentication failed")
+	// ErrInvalidSession - FreeSWITCH replied that the session(channel UUID) for a sendmsg or myevents command is invalid
+	ErrInvalidSession = errors.New("invalid session id")
+	// ErrNoSuchChannel - FreeSWITCH replied that the channel targeted by an API command does not exist
+	ErrNoSuchChannel = errors.New("no such channel")
+	// ErrCommandNotFound - FreeSWITCH does not know the command
+	ErrCommandNotFound = errors.New("command not found")
+	// ErrUsage - FreeSWITCH replied with -USAGE because the command arguments were invalid
+	ErrUsage = errors.New("invalid command usage")
+	// ErrVariableNotSet - The channel variable is not set, FreeSWITCH replied with _undef_
+	ErrVariableNotSet = errors.New("channel variable not set")
+	// ErrInlineDelimiter - The inline dialplan arguments contain a comma and every other delimiter, so the applications cannot be separated
+	ErrInlineDelimiter = errors.New("no inline dialplan delimiter left unused by the arguments")
+)
+
+// errWriteBusy - The context was done while waiting for the previous command to be answered, nothing was sent
+var errWriteBusy = errors.New("timed out waiting for the previous command")
+
+// ESLError - A -ERR or -USAGE reply from FreeSWITCH. Use errors.Is with the sentinel errors in this package to check for common reasons
+type ESLError struct {
+	Command string // The command that failed, arguments are omitted
+	Reply   string // The full first line of the reply, e.g. "-ERR No such channel!"
+	Reason  string // The reason text given by FreeSWITCH with the -ERR or -USAGE prefix removed
+}
+
+// Error - Implements the error interface
+func (e *ESLError) Error() string {
+	return e.Command + ": " + e.Reply
+}
+
+// Is - Allows errors.Is to match the sentinel errors based on the reason given by FreeSWITCH
+func (e *ESLError) Is(target error) bool {
+	reason := strings.ToLower(e.Reason)
+	switch target {
+	case ErrAuthFailed:
+		return e.Command == "auth" || e.Command == "userauth"
+	case ErrInvalidSession:
+		return strings.Contains(reason, "invalid session")
+	case ErrNoSuchChannel:
+		return strings.Contains(reason, "no such channel")
+	case ErrCommandNotFound:
+		return strings.Contains(reason, "command not found")
+	case ErrUsage:
+		return strings.HasPrefix(e.Reply, "-USAGE")
+	}
+	return false
+}
+
+// BridgeError - The bridge or intercept app completed without bridging the channel
+type BridgeError struct {
+	App         string           // The app that failed, bridge or intercept
+	Disposition string           // The originate_disposition set by FreeSWITCH e.g. USER_BUSY, empty if it was not set
+	Cause       call.HangupCause // The hangup cause of the failed leg, empty if it is unknown
+}
+
+// Error - Implements the error interface
+func (e *BridgeError) Error() string {
+	reason := e.Disposition
+	if len(reason) == 0 {
+		reason = string(e.Cause)
+	}
+	if len(reason) == 0 {
+		reason = "not bridged"
+	}
+	return e.App + " failed: " + reason
+}
+
+// newBridgeError - Builds the error from the CHANNEL_EXECUTE_COMPLETE event of the failed app
+func newBridgeError(app string, event *Event) *BridgeError {
+	bridgeErr := &BridgeError{
+		App:         app,
+		Disposition: event.GetHeader("Variable_originate_disposition"),
+	}
+	for _, header := range []string{"Variable_originate_disposition", "Variable_bridge_hangup_cause", "Variable_last_bridge_hangup_cause"} {
+		if cause, err := call.ParseHangupCause(event.GetHeader(header)); err == nil {
+			bridgeErr.Cause = cause
+			break
+		}
+	}
+	return bridgeErr
+}
+
+// Err - Returns an *ESLError if the response is a -ERR or -USAGE reply to the command, nil otherwise
+func (r RawResponse) Err(cmd command.Command) error {
+	reply := replyStatus(&r)
+	var reason string
+	switch {
+	case strings.HasPrefix(reply, "-ERR"):
+		reason = strings.TrimPrefix(reply, "-ERR")
+	case strings.HasPrefix(reply, "-USAGE"):
+		reason = strings.TrimPrefix(strings.TrimPrefix(reply, "-USAGE"), ":")
+	default:
+		return nil
+	}
+	return &ESLError{
+		Command: commandDescription(cmd.BuildMessage()),
+		Reply:   reply,
+		Reason:  strings.TrimSpace(reason),
+	}
+}
+
+// SendCommandChecked - Calls SendCommand and converts -ERR and -USAGE replies into an *ESLError. The response is returned even when it is an error reply
+func (c *Conn) SendCommandChecked(ctx context.Context, cmd command.Command) (*RawResponse, error) {
+	response, err := c.SendCommand(ctx, cmd)
+	if err != nil {
+		return response, err
+	}
+	return response, response.Err(cmd)
+}
+
+// commandDescription - Describes the command for errors without including arguments such as passwords
+func commandDescription(message string) string {
+	name := commandName(message)
+	switch name {
+	case "api", "bgapi":
+		fields := strings.Fields(strings.SplitN(message, "\n", 2)[0])
+		if len(fields) > 1 {
+			return name + " " + fields[1]
+		}
+	}
+	return name
+}
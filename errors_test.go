@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"github.com/percipia/eslgo/command"
+	"github.com/percipia/eslgo/command/call"
+	"github.com/stretchr/testify/assert"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRawResponse_Err(t *testing.T) {
+	ok := RawResponse{Headers: textproto.MIMEHeader{"Reply-Text": {"+OK"}}}
+	assert.Nil(t, ok.Err(command.Exit{}))
+
+	noChannel := RawResponse{Body: []byte("-ERR No such channel!\n")}
+	err := noChannel.Err(command.API{Command: "uuid_kill", Arguments: "a1b2"})
+	assert.True(t, errors.Is(err, ErrNoSuchChannel))
+	assert.False(t, errors.Is(err, ErrInvalidSession))
+	var eslError *ESLError
+	assert.True(t, errors.As(err, &eslError))
+	assert.Equal(t, "api uuid_kill", eslError.Command)
+	assert.Equal(t, "No such channel!", eslError.Reason)
+	assert.Equal(t, "api uuid_kill: -ERR No such channel!", err.Error())
+
+	invalidSession := RawResponse{Headers: textproto.MIMEHeader{"Reply-Text": {"-ERR invalid session id [a1b2]"}}}
+	assert.True(t, errors.Is(invalidSession.Err(call.Hangup{UUID: "a1b2"}), ErrInvalidSession))
+
+	usage := RawResponse{Body: []byte("-USAGE: <uuid> [cause]\n")}
+	err = usage.Err(command.API{Command: "uuid_kill"})
+	assert.True(t, errors.Is(err, ErrUsage))
+	assert.True(t, errors.As(err, &eslError))
+	assert.Equal(t, "<uuid> [cause]", eslError.Reason)
+
+	// Passwords must never end up in the error text
+	authFailed := RawResponse{Headers: textproto.MIMEHeader{"Reply-Text": {"-ERR invalid"}}}
+	err = authFailed.Err(command.Auth{Password: "secret"})
+	assert.True(t, errors.Is(err, ErrAuthFailed))
+	assert.NotContains(t, err.Error(), "secret")
+}
+
+func TestInboundOptions_Dial_AuthFailed(t *testing.T) {
+	server := newTestServer(t, func(command string) string {
+		if strings.HasPrefix(command, "auth") {
+			return testReply("-ERR invalid")
+		}
+		return testReply("+OK")
+	})
+
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	opts.ExitTimeout = 100 * time.Millisecond
+	_, err := opts.Dial(server.Address())
+	assert.True(t, errors.Is(err, ErrAuthFailed))
+}
+
+func TestConn_SendCommandChecked_Closed(t *testing.T) {
+	server := newTestServer(t, func(command string) string {
+		return testReply("+OK")
+	})
+
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	conn, err := opts.Dial(server.Address())
+	assert.Nil(t, err)
+	conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = conn.SendCommandChecked(ctx, command.API{Command: "status"})
+	assert.True(t, errors.Is(err, ErrConnectionClosed))
+}
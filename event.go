@@ -13,7 +13,9 @@ package eslgo
 import (
 	"bufio"
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"github.com/percipia/eslgo/command/call"
 	"io"
 	"net/textproto"
 	"net/url"
@@ -26,6 +28,7 @@ type EventListener func(event *Event)
 type Event struct {
 	Headers textproto.MIMEHeader
 	Body    []byte
+	JSON    json.RawMessage // The event exactly as FreeSWITCH sent it when subscribed in the json format, nil for other formats
 }
 
 const (
@@ -66,11 +69,34 @@ func readXMLEvent(body []byte) (*Event, error) {
 	}, nil
 }
 
-// TODO: Needs processing
+// readJSONEvent - Parses a text/event-json body. Values are escaped the same way as plain events so GetHeader works for both formats
+// FreeSWITCH sends headers that are set more than once as an array, the body is sent as _body
 func readJSONEvent(body []byte) (*Event, error) {
-	return &Event{
-		Headers: make(textproto.MIMEHeader),
-	}, nil
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	event := &Event{
+		Headers: make(textproto.MIMEHeader, len(fields)),
+		JSON:    append(json.RawMessage(nil), body...),
+	}
+	for key, raw := range fields {
+		var values []string
+		var value string
+		if err := json.Unmarshal(raw, &value); err == nil {
+			values = []string{value}
+		} else if err = json.Unmarshal(raw, &values); err != nil {
+			return event, fmt.Errorf("event header %s: %w", key, err)
+		}
+		if key == "_body" {
+			event.Body = []byte(value)
+			continue
+		}
+		for _, value := range values {
+			event.Headers.Add(key, url.PathEscape(value))
+		}
+	}
+	return event, nil
 }
 
 // GetName Helper function that returns the event name header
@@ -78,6 +104,25 @@ func (e Event) GetName() string {
 	return e.GetHeader("Event-Name")
 }
 
+// HangupCause Helper to get the hangup cause of hangup events. Uses the Hangup-Cause header and falls back to the
+// hangup_cause, hangup_cause_q850 and sip_term_status channel variables. Returns an empty cause if none are set
+func (e Event) HangupCause() call.HangupCause {
+	for _, header := range []string{"Hangup-Cause", "Variable_hangup_cause"} {
+		if cause, err := call.ParseHangupCause(e.GetHeader(header)); err == nil {
+			return cause
+		}
+	}
+	if code, err := strconv.Atoi(e.GetHeader("Variable_hangup_cause_q850")); err == nil {
+		if cause, ok := call.HangupCauseFromQ850(code); ok {
+			return cause
+		}
+	}
+	if status, err := strconv.Atoi(e.GetHeader("Variable_sip_term_status")); err == nil {
+		return call.HangupCauseFromSIPStatus(status)
+	}
+	return ""
+}
+
 // HasHeader Helper to check if the Event has a header
 func (e Event) HasHeader(header string) bool {
 	_, ok := e.Headers[textproto.CanonicalMIMEHeaderKey(header)]
@@ -11,10 +11,15 @@
 package eslgo
 
 import (
+	"fmt"
+	"github.com/percipia/eslgo/command/call"
 	"github.com/stretchr/testify/assert"
 	"net"
+	"net/textproto"
+	"strconv"
 	"sync"
 	"testing"
+	"time"
 )
 
 const TestEventToSend = "Content-Length: 483\r\nContent-Type: text/event-plain\r\n\r\nMessage-Account: sip%3A1006%4010.0.1.250\r\nEvent-Name: MESSAGE_QUERY\r\nCore-UUID: 2130a7d1-c1f7-44cd-8fae-8ed5946f3cec\r\nFreeSWITCH-Hostname: localhost.localdomain\r\nFreeSWITCH-IPv4: 10.0.1.250\r\nFreeSWITCH-IPv6: 127.0.0.1\r\nEvent-Date-Local: 2007-12-16%2022%3A29%3A59\r\nEvent-Date-GMT: Mon,%2017%20Dec%202007%2004%3A29%3A59%20GMT\r\nEvent-Date-timestamp: 1197865799573052\r\nEvent-Calling-File: sofia_reg.c\r\nEvent-Calling-Function: sofia_reg_handle_register\r\nEvent-Calling-Line-Number: 603\r\n\r\n"
@@ -39,3 +44,103 @@ func TestEvent_readPlainEvent(t *testing.T) {
 	assert.Nil(t, err)
 	wait.Wait()
 }
+
+func TestEvent_listenerPanic(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.Logger = NilLogger{}
+
+	var wait sync.WaitGroup
+	wait.Add(1)
+	opts.OnPanic = func(recovered interface{}, stack []byte) {
+		assert.Equal(t, "listener exploded", recovered)
+		assert.NotEmpty(t, stack)
+		wait.Done()
+	}
+	connection := newConnection(client, false, opts)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	connection.RegisterEventListener(EventListenAll, func(event *Event) {
+		panic("listener exploded")
+	})
+
+	_, err := server.Write([]byte(TestEventToSend))
+	assert.Nil(t, err)
+	wait.Wait()
+}
+
+func TestEvent_HangupCause(t *testing.T) {
+	event := &Event{Headers: textproto.MIMEHeader{
+		"Hangup-Cause":               {"USER_BUSY"},
+		"Variable_hangup_cause_q850": {"16"},
+	}}
+	assert.Equal(t, call.CauseUserBusy, event.HangupCause())
+
+	event = &Event{Headers: textproto.MIMEHeader{
+		"Hangup-Cause":               {"NORMAL_CLEARNG"},
+		"Variable_hangup_cause_q850": {"19"},
+	}}
+	assert.Equal(t, call.CauseNoAnswer, event.HangupCause())
+
+	event = &Event{Headers: textproto.MIMEHeader{
+		"Variable_sip_term_status": {"486"},
+	}}
+	assert.Equal(t, call.CauseUserBusy, event.HangupCause())
+
+	assert.Equal(t, call.HangupCause(""), (&Event{Headers: textproto.MIMEHeader{}}).HangupCause())
+}
+
+func TestEvent_readJSONEvent(t *testing.T) {
+	body := `{"Event-Name":"CHANNEL_CREATE","Unique-ID":"a-leg","variable_sip_call_id":"abc 100%","Multi":["1","2"],"_body":"hello"}`
+	event, err := readJSONEvent([]byte(body))
+	assert.Nil(t, err)
+	assert.Equal(t, "CHANNEL_CREATE", event.GetName())
+	assert.Equal(t, "a-leg", event.GetHeader("Unique-ID"))
+	assert.Equal(t, "abc 100%", event.GetHeader("variable_sip_call_id"))
+	assert.Equal(t, []string{"1", "2"}, event.Headers["Multi"])
+	assert.Equal(t, "hello", string(event.Body))
+	assert.JSONEq(t, body, string(event.JSON))
+
+	_, err = readJSONEvent([]byte(`{"Event-Name":1}`))
+	assert.Error(t, err)
+}
+
+func TestConn_RegisterOrderedEventListener(t *testing.T) {
+	server, client := net.Pipe()
+	connection := newConnection(client, false, DefaultOptions)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	var lock sync.Mutex
+	var sequences []string
+	id := connection.RegisterOrderedEventListener(EventListenAll, func(event *Event) {
+		lock.Lock()
+		defer lock.Unlock()
+		sequences = append(sequences, event.GetHeader("Event-Sequence"))
+	})
+
+	var expected []string
+	for i := 0; i < 50; i++ {
+		sequence := strconv.Itoa(i)
+		expected = append(expected, sequence)
+		body := "Event-Name: HEARTBEAT\nEvent-Sequence: " + sequence + "\n\n"
+		_, err := server.Write([]byte(fmt.Sprintf("Content-Length: %d\r\nContent-Type: text/event-plain\r\n\r\n%s", len(body), body)))
+		assert.Nil(t, err)
+	}
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(sequences) == len(expected)
+	}, 5*time.Second, time.Millisecond)
+	lock.Lock()
+	assert.Equal(t, expected, sequences)
+	lock.Unlock()
+
+	connection.RemoveEventListener(EventListenAll, id)
+	connection.eventListenerLock.RLock()
+	assert.Empty(t, connection.orderedListeners[EventListenAll])
+	connection.eventListenerLock.RUnlock()
+}
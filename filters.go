@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import "github.com/percipia/eslgo/command"
+
+// Filters - Lists the event filters active on the connection. FreeSWITCH has no command to list them so they are tracked as filter commands are sent
+func (c *Conn) Filters() []command.Filter {
+	c.filterLock.Lock()
+	defer c.filterLock.Unlock()
+	return append([]command.Filter(nil), c.filters...)
+}
+
+// trackFilter - Updates the active filters after FreeSWITCH accepted the filter command
+func (c *Conn) trackFilter(filter command.Filter) {
+	c.filterLock.Lock()
+	defer c.filterLock.Unlock()
+
+	if !filter.Delete {
+		for _, existing := range c.filters {
+			if existing.EventHeader == filter.EventHeader && existing.FilterValue == filter.FilterValue {
+				return
+			}
+		}
+		c.filters = append(c.filters, filter)
+		return
+	}
+
+	if filter.EventHeader == "all" {
+		c.filters = nil
+		return
+	}
+	remaining := c.filters[:0]
+	for _, existing := range c.filters {
+		// Deleting without a value removes every filter on the header
+		matches := existing.EventHeader == filter.EventHeader && (len(filter.FilterValue) == 0 || existing.FilterValue == filter.FilterValue)
+		if !matches {
+			remaining = append(remaining, existing)
+		}
+	}
+	c.filters = remaining
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/percipia/eslgo/command"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConn_Filters(t *testing.T) {
+	conn, _ := dialTestServer(t, func(command string) string {
+		if strings.HasSuffix(command, "invalid") {
+			return testReply("-ERR invalid syntax")
+		}
+		return testReply("+OK filter added")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	send := func(filter command.Filter) {
+		_, err := conn.SendCommand(ctx, filter)
+		assert.Nil(t, err)
+	}
+
+	send(command.Filter{EventHeader: "Event-Name", FilterValue: "CHANNEL_ANSWER"})
+	send(command.Filter{EventHeader: "Event-Name", FilterValue: "CHANNEL_HANGUP"})
+	send(command.Filter{EventHeader: "Unique-ID", FilterValue: "channel"})
+	send(command.Filter{EventHeader: "Unique-ID", FilterValue: "channel"})
+	send(command.Filter{EventHeader: "Caller-Context", FilterValue: "invalid"})
+	assert.Len(t, conn.Filters(), 3)
+
+	send(command.Filter{Delete: true, EventHeader: "Event-Name"})
+	assert.Equal(t, []command.Filter{{EventHeader: "Unique-ID", FilterValue: "channel"}}, conn.Filters())
+
+	send(command.Filter{Delete: true, EventHeader: "all"})
+	assert.Empty(t, conn.Filters())
+}
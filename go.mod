@@ -1,8 +1,18 @@
 module github.com/percipia/eslgo
 
-go 1.14
+go 1.21
 
 require (
-	github.com/google/uuid v1.3.0
-	github.com/stretchr/testify v1.7.0
+	github.com/google/uuid v1.6.0
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/sys v0.22.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/pretty v0.3.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rogpeppe/go-internal v1.10.0 // indirect
+	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -102,18 +102,11 @@ func (c *Conn) WaitForDTMF(ctx context.Context, uuid string) (byte, error) {
 
 // Helper for mod_dptools apps since they are very similar in invocation
 func (c *Conn) audioCommand(ctx context.Context, command, uuid, audioArgs string, times int, wait bool) (*RawResponse, error) {
-	response, err := c.SendCommand(ctx, &call.Execute{
+	return c.SendCommandChecked(ctx, &call.Execute{
 		UUID:    uuid,
 		AppName: command,
 		AppArgs: audioArgs,
 		Loops:   times,
 		Sync:    wait,
 	})
-	if err != nil {
-		return response, err
-	}
-	if !response.IsOk() {
-		return response, errors.New(command + " response is not okay")
-	}
-	return response, nil
 }
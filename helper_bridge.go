@@ -0,0 +1,265 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/percipia/eslgo/command"
+	"github.com/percipia/eslgo/command/call"
+	"strconv"
+	"strings"
+)
+
+// LegGroup - Legs that are dialed simultaneously when bridging, joined with ","
+type LegGroup []Leg
+
+// TransferLeg - Which legs of a bridged call uuid_transfer should transfer
+type TransferLeg string
+
+const (
+	TransferALeg TransferLeg = ""
+	TransferBLeg TransferLeg = "-bleg"
+	TransferBoth TransferLeg = "-both"
+)
+
+// EavesdropOptions - Channel variables controlling the mod_dptools eavesdrop app
+type EavesdropOptions struct {
+	EnableDTMF   bool   // Allow the eavesdropper to use DTMF: 1 talks to the A leg, 2 talks to the B leg, 3 is a three-way call and 0 restores listening only
+	WhisperALeg  bool   // Start by talking to the A leg
+	WhisperBLeg  bool   // Start by talking to the B leg
+	BridgeALeg   bool   // Start with the eavesdropper bridged to the A leg
+	BridgeBLeg   bool   // Start with the eavesdropper bridged to the B leg
+	RequireGroup string // Only eavesdrop on channels in this eavesdrop group when eavesdropping on "all"
+}
+
+// BridgeCall - Bridges two existing channels together using uuid_bridge. If wait is true waits for the CHANNEL_BRIDGE event on the aLeg, requires events to be enabled!
+func (c *Conn) BridgeCall(ctx context.Context, aLegUUID, bLegUUID string, wait bool) error {
+	return c.commandAndWait(ctx, command.API{
+		Command:   "uuid_bridge",
+		Arguments: fmt.Sprintf("%s %s", aLegUUID, bLegUUID),
+	}, aLegUUID, "CHANNEL_BRIDGE", wait)
+}
+
+// Bridge - Executes the mod_dptools bridge app on the channel. Each LegGroup is dialed simultaneously and the groups are tried in order until one answers
+// vars are channel variables for all legs, contained in {}. If wait is true waits for the CHANNEL_BRIDGE event, requires events to be enabled!
+// If the bridge app completes without bridging a *BridgeError with the originate disposition is returned
+func (c *Conn) Bridge(ctx context.Context, uuid string, wait bool, vars map[string]string, groups ...LegGroup) error {
+	if len(groups) == 0 {
+		return errors.New("no legs specified")
+	}
+	return c.executeAndWaitBridge(ctx, &call.Execute{
+		UUID:    uuid,
+		AppName: "bridge",
+		AppArgs: BuildVars("{%s}", vars) + BuildBridgeString(groups...),
+	}, wait)
+}
+
+// Intercept - Executes the mod_dptools intercept app, connecting the channel to the targetUUID channel. If bLeg is true the other leg of targetUUID is intercepted instead
+// If wait is true waits for the CHANNEL_BRIDGE event, requires events to be enabled! If the intercept app completes without bridging a *BridgeError is returned
+func (c *Conn) Intercept(ctx context.Context, uuid, targetUUID string, bLeg, wait bool) error {
+	args := targetUUID
+	if bLeg {
+		args = "-bleg " + targetUUID
+	}
+	return c.executeAndWaitBridge(ctx, &call.Execute{
+		UUID:    uuid,
+		AppName: "intercept",
+		AppArgs: args,
+	}, wait)
+}
+
+// Eavesdrop - Executes the mod_dptools eavesdrop app, listening to targetUUID. targetUUID can be "all" to listen to every call
+func (c *Conn) Eavesdrop(ctx context.Context, uuid, targetUUID string, opts EavesdropOptions) error {
+	vars := [][2]string{
+		{"eavesdrop_enable_dtmf", strconv.FormatBool(opts.EnableDTMF)},
+		{"eavesdrop_whisper_aleg", strconv.FormatBool(opts.WhisperALeg)},
+		{"eavesdrop_whisper_bleg", strconv.FormatBool(opts.WhisperBLeg)},
+		{"eavesdrop_bridge_aleg", strconv.FormatBool(opts.BridgeALeg)},
+		{"eavesdrop_bridge_bleg", strconv.FormatBool(opts.BridgeBLeg)},
+	}
+	if len(opts.RequireGroup) > 0 {
+		vars = append(vars, [2]string{"eavesdrop_require_group", opts.RequireGroup})
+	}
+	for _, variable := range vars {
+		_, err := c.SendCommandChecked(ctx, call.Set{
+			UUID:  uuid,
+			Key:   variable[0],
+			Value: variable[1],
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := c.SendCommandChecked(ctx, &call.Execute{
+		UUID:    uuid,
+		AppName: "eavesdrop",
+		AppArgs: targetUUID,
+	})
+	return err
+}
+
+// ThreeWay - Executes the mod_dptools three_way app, joining the channel into the call targetUUID is part of
+func (c *Conn) ThreeWay(ctx context.Context, uuid, targetUUID string) error {
+	_, err := c.SendCommandChecked(ctx, &call.Execute{
+		UUID:    uuid,
+		AppName: "three_way",
+		AppArgs: targetUUID,
+	})
+	return err
+}
+
+// TransferCall - Transfers the channel to the destination using uuid_transfer. dialplan and dialplanContext are optional
+func (c *Conn) TransferCall(ctx context.Context, uuid string, leg TransferLeg, destination, dialplan, dialplanContext string) error {
+	args := []string{uuid}
+	if len(leg) > 0 {
+		args = append(args, string(leg))
+	}
+	args = append(args, destination)
+	if len(dialplan) > 0 {
+		args = append(args, dialplan)
+		if len(dialplanContext) > 0 {
+			args = append(args, dialplanContext)
+		}
+	}
+	_, err := c.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_transfer",
+		Arguments: strings.Join(args, " "),
+	})
+	return err
+}
+
+// HoldCall - Places the channel on hold or takes it off hold using uuid_hold
+func (c *Conn) HoldCall(ctx context.Context, uuid string, hold bool) error {
+	args := uuid
+	if !hold {
+		args = "off " + uuid
+	}
+	_, err := c.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_hold",
+		Arguments: args,
+	})
+	return err
+}
+
+// ParkCall - Parks the channel using uuid_park, unbridging it if it is bridged
+func (c *Conn) ParkCall(ctx context.Context, uuid string) error {
+	_, err := c.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_park",
+		Arguments: uuid,
+	})
+	return err
+}
+
+// WaitForBridge - Waits for a CHANNEL_BRIDGE event for the channel. Requires events to be enabled!
+func (c *Conn) WaitForBridge(ctx context.Context, uuid string) (*Event, error) {
+	return c.WaitForEvent(ctx, uuid, "CHANNEL_BRIDGE")
+}
+
+// WaitForUnbridge - Waits for a CHANNEL_UNBRIDGE event for the channel. Requires events to be enabled!
+func (c *Conn) WaitForUnbridge(ctx context.Context, uuid string) (*Event, error) {
+	return c.WaitForEvent(ctx, uuid, "CHANNEL_UNBRIDGE")
+}
+
+// WaitForEvent - Waits for the next event with the name for the channel, application or job UUID. Requires events to be enabled!
+func (c *Conn) WaitForEvent(ctx context.Context, uuid, name string) (*Event, error) {
+	events, remove := c.awaitEvent(uuid, name)
+	defer remove()
+
+	select {
+	case event := <-events:
+		return event, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// BuildBridgeString - Builds a bridge dial string, legs in a group are dialed simultaneously(",") and groups are tried in order(|)
+func BuildBridgeString(groups ...LegGroup) string {
+	return DialBranch{Groups: groups}.String()
+}
+
+// awaitEvent - Registers a listener for the next event with the name. Register before sending the command that causes the event so it cannot be missed
+// The listener is ordered so the event kept is the first one FreeSWITCH sent
+func (c *Conn) awaitEvent(uuid, name string) (<-chan *Event, func()) {
+	events := make(chan *Event, 1)
+	id := c.RegisterOrderedEventListener(uuid, func(event *Event) {
+		if event.GetName() == name {
+			select {
+			case events <- event:
+			default:
+			}
+		}
+	})
+	return events, func() {
+		c.RemoveEventListener(uuid, id)
+	}
+}
+
+// commandAndWait - Sends the command and if wait is true waits for the event with the name for the UUID
+func (c *Conn) commandAndWait(ctx context.Context, cmd command.Command, uuid, name string, wait bool) error {
+	if !wait {
+		_, err := c.SendCommandChecked(ctx, cmd)
+		return err
+	}
+
+	events, remove := c.awaitEvent(uuid, name)
+	defer remove()
+	if _, err := c.SendCommandChecked(ctx, cmd); err != nil {
+		return err
+	}
+	select {
+	case <-events:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// executeAndWaitBridge - Executes the app and if wait is true waits for the channel to be bridged. The execute is tagged with an Event-UUID so
+// the CHANNEL_EXECUTE_COMPLETE of this app can be told apart, if it arrives before CHANNEL_BRIDGE the app failed to bridge the channel
+func (c *Conn) executeAndWaitBridge(ctx context.Context, execute *call.Execute, wait bool) error {
+	if !wait {
+		_, err := c.SendCommandChecked(ctx, execute)
+		return err
+	}
+
+	execute.AppUUID = uuid.New().String()
+	events := make(chan *Event, 1)
+	// Ordered so only the first of CHANNEL_BRIDGE and CHANNEL_EXECUTE_COMPLETE is kept, unordered listeners could run the other way round
+	id := c.RegisterOrderedEventListener(execute.UUID, func(event *Event) {
+		name := event.GetName()
+		if name != "CHANNEL_BRIDGE" && (name != "CHANNEL_EXECUTE_COMPLETE" || event.GetHeader("Application-UUID") != execute.AppUUID) {
+			return
+		}
+		select {
+		case events <- event:
+		default:
+		}
+	})
+	defer c.RemoveEventListener(execute.UUID, id)
+
+	if _, err := c.SendCommandChecked(ctx, execute); err != nil {
+		return err
+	}
+	select {
+	case event := <-events:
+		if event.GetName() == "CHANNEL_BRIDGE" {
+			return nil
+		}
+		return newBridgeError(execute.AppName, event)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
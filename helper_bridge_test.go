@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/percipia/eslgo/command/call"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// dialTestServer - Dials a test server recording every command received after authentication
+func dialTestServer(t *testing.T, respond func(command string) string) (*Conn, func() []string) {
+	var lock sync.Mutex
+	var commands []string
+	server := newTestServer(t, func(command string) string {
+		if strings.HasPrefix(command, "auth") {
+			return testReply("+OK accepted")
+		}
+		lock.Lock()
+		commands = append(commands, command)
+		lock.Unlock()
+		return respond(command)
+	})
+
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	opts.ExitTimeout = 100 * time.Millisecond
+	conn, err := opts.Dial(server.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(conn.Close)
+	return conn, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string(nil), commands...)
+	}
+}
+
+func TestConn_BridgeCall(t *testing.T) {
+	conn, commands := dialTestServer(t, func(command string) string {
+		return testAPIResponse("+OK") + testEvent("Event-Name: CHANNEL_BRIDGE", "Unique-ID: a-leg", "Other-Leg-Unique-ID: b-leg")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, conn.BridgeCall(ctx, "a-leg", "b-leg", true))
+	assert.Equal(t, []string{"api uuid_bridge a-leg b-leg"}, commands())
+}
+
+func TestConn_TransferCall(t *testing.T) {
+	conn, commands := dialTestServer(t, func(command string) string {
+		if strings.Contains(command, "missing") {
+			return testAPIResponse("-ERR No such channel!\n")
+		}
+		return testAPIResponse("+OK\n")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, conn.TransferCall(ctx, "a-leg", TransferBoth, "1000", "XML", "default"))
+	assert.Nil(t, conn.HoldCall(ctx, "a-leg", false))
+	assert.Nil(t, conn.ParkCall(ctx, "a-leg"))
+	assert.True(t, errors.Is(conn.TransferCall(ctx, "missing", TransferALeg, "1000", "", ""), ErrNoSuchChannel))
+	assert.Equal(t, []string{
+		"api uuid_transfer a-leg -both 1000 XML default",
+		"api uuid_hold off a-leg",
+		"api uuid_park a-leg",
+		"api uuid_transfer missing 1000",
+	}, commands())
+}
+
+func TestBuildBridgeString(t *testing.T) {
+	assert.Equal(t, "user/100,user/101|sofia/gateway/backup/100", BuildBridgeString(
+		LegGroup{{CallURL: "user/100"}, {CallURL: "user/101"}},
+		LegGroup{{CallURL: "sofia/gateway/backup/100"}},
+	))
+}
+
+func TestConn_Bridge_Failed(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.Logger = NilLogger{}
+	connection := newConnection(client, false, opts)
+	defer connection.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- connection.Bridge(ctx, "a-leg", true, nil, LegGroup{{CallURL: "user/1000"}})
+	}()
+
+	reader := textproto.NewReader(bufio.NewReader(server))
+	line, err := reader.ReadLine()
+	assert.Nil(t, err)
+	assert.Equal(t, "sendmsg a-leg", line)
+	headers, err := reader.ReadMIMEHeader()
+	assert.Nil(t, err)
+	appUUID := headers.Get("Event-UUID")
+	assert.NotEmpty(t, appUUID)
+
+	_, err = server.Write([]byte(testReply("+OK")))
+	assert.Nil(t, err)
+	// A different app completing on the channel must not end the wait
+	_, err = server.Write([]byte(testEvent("Event-Name: CHANNEL_EXECUTE_COMPLETE", "Unique-ID: a-leg", "Application-UUID: other")))
+	assert.Nil(t, err)
+	_, err = server.Write([]byte(testEvent("Event-Name: CHANNEL_EXECUTE_COMPLETE", "Unique-ID: a-leg", "Application-UUID: "+appUUID,
+		"variable_originate_disposition: USER_BUSY", "variable_bridge_hangup_cause: USER_BUSY")))
+	assert.Nil(t, err)
+
+	select {
+	case err = <-result:
+	case <-time.After(2 * time.Second):
+		t.Fatal("bridge failure did not end the wait")
+	}
+	var bridgeErr *BridgeError
+	assert.True(t, errors.As(err, &bridgeErr))
+	assert.Equal(t, "bridge", bridgeErr.App)
+	assert.Equal(t, "USER_BUSY", bridgeErr.Disposition)
+	assert.Equal(t, call.CauseUserBusy, bridgeErr.Cause)
+	assert.Equal(t, "bridge failed: USER_BUSY", err.Error())
+}
+
+func TestConn_Bridge_EventOrder(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.Logger = NilLogger{}
+	connection := newConnection(client, false, opts)
+	defer connection.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result := make(chan error, 1)
+	go func() {
+		result <- connection.Bridge(ctx, "a-leg", true, nil, LegGroup{{CallURL: "user/1000"}})
+	}()
+
+	reader := textproto.NewReader(bufio.NewReader(server))
+	_, err := reader.ReadLine()
+	assert.Nil(t, err)
+	headers, err := reader.ReadMIMEHeader()
+	assert.Nil(t, err)
+
+	// The app completes right after the bridge ends, the bridge came first so the bridge succeeded
+	_, err = server.Write([]byte(testReply("+OK") +
+		testEvent("Event-Name: CHANNEL_BRIDGE", "Unique-ID: a-leg") +
+		testEvent("Event-Name: CHANNEL_EXECUTE_COMPLETE", "Unique-ID: a-leg", "Application-UUID: "+headers.Get("Event-UUID"), "variable_bridge_hangup_cause: NORMAL_CLEARING")))
+	assert.Nil(t, err)
+	select {
+	case err = <-result:
+		assert.Nil(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("bridge did not end the wait")
+	}
+}
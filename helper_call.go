@@ -30,14 +30,8 @@ type Leg struct {
 // aLeg, bLeg Leg The aLeg and bLeg of the call respectively
 // vars map[string]string, channel variables to be passed to originate for both legs, contained in {}
 func (c *Conn) OriginateCall(ctx context.Context, background bool, aLeg, bLeg Leg, vars map[string]string) (*RawResponse, error) {
-	if vars == nil {
-		vars = make(map[string]string)
-	}
-
-	if _, ok := vars["origination_uuid"]; ok {
-		// We cannot set origination uuid globally
-		delete(vars, "origination_uuid")
-	}
+	// origination_uuid cannot be set globally, it only applies to the aLeg
+	vars, aLeg = moveOriginationUUID(vars, aLeg)
 
 	response, err := c.SendCommand(ctx, command.API{
 		Command:    "originate",
@@ -59,12 +53,13 @@ func (c *Conn) EnterpriseOriginateCall(ctx context.Context, background bool, var
 		return nil, errors.New("no aLeg specified")
 	}
 
-	if vars == nil {
-		vars = make(map[string]string)
-	}
-
-	if _, ok := vars["origination_uuid"]; ok {
-		// We cannot set origination uuid globally
+	if len(aLegs) == 1 {
+		aLegs = []Leg{aLegs[0]}
+		// origination_uuid cannot be set globally, move it to the only aLeg
+		vars, aLegs[0] = moveOriginationUUID(vars, aLegs[0])
+	} else if _, ok := vars["origination_uuid"]; ok {
+		// Every aLeg would get the same UUID, it has to be set per leg instead
+		vars = copyVars(vars)
 		delete(vars, "origination_uuid")
 	}
 
@@ -86,8 +81,8 @@ func (c *Conn) EnterpriseOriginateCall(ctx context.Context, background bool, var
 }
 
 // HangupCall - A helper to hangup a call asynchronously
-func (c *Conn) HangupCall(ctx context.Context, uuid, cause string) error {
-	_, err := c.SendCommand(ctx, call.Hangup{
+func (c *Conn) HangupCall(ctx context.Context, uuid string, cause call.HangupCause) error {
+	_, err := c.SendCommandChecked(ctx, call.Hangup{
 		UUID:  uuid,
 		Cause: cause,
 		Sync:  false,
@@ -97,14 +92,31 @@ func (c *Conn) HangupCall(ctx context.Context, uuid, cause string) error {
 
 // HangupCall - A helper to answer a call synchronously
 func (c *Conn) AnswerCall(ctx context.Context, uuid string) error {
-	_, err := c.SendCommand(ctx, &call.Execute{
-		UUID:    uuid,
-		AppName: "answer",
-		Sync:    true,
+	_, err := c.SendCommandChecked(ctx, call.Answer{
+		UUID: uuid,
+		Sync: true,
 	})
 	return err
 }
 
+// moveOriginationUUID - Moves origination_uuid from the global variables to the leg unless the leg sets its own. Neither map passed in is modified
+func moveOriginationUUID(vars map[string]string, leg Leg) (map[string]string, Leg) {
+	originationUUID, ok := vars["origination_uuid"]
+	if !ok {
+		return vars, leg
+	}
+	vars = copyVars(vars)
+	delete(vars, "origination_uuid")
+	if _, ok := leg.LegVariables["origination_uuid"]; !ok {
+		leg.LegVariables = copyVars(leg.LegVariables)
+		if leg.LegVariables == nil {
+			leg.LegVariables = make(map[string]string)
+		}
+		leg.LegVariables["origination_uuid"] = originationUUID
+	}
+	return vars, leg
+}
+
 // String - Build the Leg string for passing to Bridge/Originate functions
 func (l Leg) String() string {
 	return fmt.Sprintf("%s%s", BuildVars("[%s]", l.LegVariables), l.CallURL)
@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"github.com/google/uuid"
+	"github.com/percipia/eslgo/command"
+	"github.com/percipia/eslgo/command/call"
+	"strings"
+)
+
+// OriginateResult - The outcome of an originate
+type OriginateResult struct {
+	UUID     string           // The UUID of the channel that answered, empty on failure
+	LegUUIDs []string         // The origination_uuid of every leg that was dialed, in dial string order
+	Cause    call.HangupCause // Why the originate failed, e.g. USER_BUSY. Empty on success
+	Reply    string           // The first line of the reply from FreeSWITCH
+}
+
+// OriginateJob - A background originate that resolves when FreeSWITCH sends the BACKGROUND_JOB event
+type OriginateJob struct {
+	JobUUID  string   // The Job-UUID of the bgapi command
+	LegUUIDs []string // The origination_uuid of every leg being dialed, usable to track the legs before the job completes
+
+	done   chan struct{}
+	result *OriginateResult
+	err    error
+}
+
+// Done - Closed once the job completed or the connection was closed
+func (j *OriginateJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Wait - Waits for the job to complete. The error is an *ESLError when the originate failed, the result is still returned with the hangup cause
+func (j *OriginateJob) Wait(ctx context.Context) (*OriginateResult, error) {
+	select {
+	case <-j.done:
+		return j.result, j.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Originate - Originates the dial string, connecting the answered channel to the destination
+// destination is an extension optionally followed by the dialplan and context, or an application e.g. "&park()"
+// Every leg gets an origination_uuid, generated when not already set in the leg variables, so each leg can be tracked.
+// When the originate fails the error is an *ESLError and the result holds the hangup cause
+func (c *Conn) Originate(ctx context.Context, dialString DialString, destination string) (*OriginateResult, error) {
+	dialString, legUUIDs, err := withOriginationUUIDs(dialString)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := command.API{
+		Command:   "originate",
+		Arguments: dialString.String() + " " + destination,
+	}
+	response, err := c.SendCommand(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+	result := parseOriginateReply(replyStatus(response), legUUIDs)
+	return result, response.Err(cmd)
+}
+
+// OriginateBackground - Like Originate but uses bgapi, returning as soon as FreeSWITCH accepted the job. Requires BACKGROUND_JOB events to be enabled!
+func (c *Conn) OriginateBackground(ctx context.Context, dialString DialString, destination string) (*OriginateJob, error) {
+	dialString, legUUIDs, err := withOriginationUUIDs(dialString)
+	if err != nil {
+		return nil, err
+	}
+
+	job := &OriginateJob{
+		JobUUID:  uuid.New().String(),
+		LegUUIDs: legUUIDs,
+		done:     make(chan struct{}),
+	}
+	cmd := command.API{
+		Command:    "originate",
+		Arguments:  dialString.String() + " " + destination,
+		Background: true,
+		JobUUID:    job.JobUUID,
+	}
+
+	// Listen before sending so a quick failure cannot be missed
+	events, remove := c.awaitEvent(job.JobUUID, "BACKGROUND_JOB")
+	if _, err := c.SendCommandChecked(ctx, cmd); err != nil {
+		remove()
+		return nil, err
+	}
+
+	go func() {
+		defer close(job.done)
+		defer remove()
+		select {
+		case event := <-events:
+			reply := strings.TrimSpace(strings.SplitN(string(event.Body), "\n", 2)[0])
+			job.result = parseOriginateReply(reply, legUUIDs)
+			job.err = RawResponse{Body: []byte(reply)}.Err(cmd)
+		case <-c.runningContext.Done():
+			job.err = ErrConnectionClosed
+		}
+	}()
+	return job, nil
+}
+
+// parseOriginateReply - Parses "+OK <uuid>" or "-ERR <cause>" replies to originate
+func parseOriginateReply(reply string, legUUIDs []string) *OriginateResult {
+	result := &OriginateResult{
+		LegUUIDs: legUUIDs,
+		Reply:    reply,
+	}
+	switch {
+	case strings.HasPrefix(reply, "+OK"):
+		result.UUID = strings.TrimSpace(strings.TrimPrefix(reply, "+OK"))
+	case strings.HasPrefix(reply, "-ERR"):
+		// Unknown causes are kept as is so they are not lost, check HangupCause.Valid if needed
+		result.Cause, _ = call.ParseHangupCause(strings.TrimPrefix(reply, "-ERR"))
+	}
+	return result
+}
+
+// withOriginationUUIDs - Returns a copy of the dial string where every leg has an origination_uuid along with the UUIDs in dial string order
+// origination_uuid can only be set globally when a single leg is dialed, it is moved to that leg
+func withOriginationUUIDs(dialString DialString) (DialString, []string, error) {
+	var legs []*Leg
+	copied := DialString{
+		Variables: copyVars(dialString.Variables),
+		Branches:  make([]DialBranch, len(dialString.Branches)),
+	}
+	for i, branch := range dialString.Branches {
+		copied.Branches[i] = DialBranch{
+			Variables: copyVars(branch.Variables),
+			Groups:    make([]LegGroup, len(branch.Groups)),
+		}
+		for j, group := range branch.Groups {
+			copied.Branches[i].Groups[j] = make(LegGroup, len(group))
+			for k, leg := range group {
+				copied.Branches[i].Groups[j][k] = Leg{
+					CallURL:      leg.CallURL,
+					LegVariables: copyVars(leg.LegVariables),
+				}
+				legs = append(legs, &copied.Branches[i].Groups[j][k])
+			}
+		}
+	}
+	if len(legs) == 0 {
+		return copied, nil, errors.New("no legs specified")
+	}
+
+	globalUUID, err := takeOriginationUUID(copied.Variables, "")
+	if err != nil {
+		return copied, nil, err
+	}
+	for _, branch := range copied.Branches {
+		if globalUUID, err = takeOriginationUUID(branch.Variables, globalUUID); err != nil {
+			return copied, nil, err
+		}
+	}
+	if len(globalUUID) > 0 && len(legs) > 1 {
+		return copied, nil, errors.New("origination_uuid must be set per leg when dialing multiple legs")
+	}
+
+	legUUIDs := make([]string, 0, len(legs))
+	for _, leg := range legs {
+		if leg.LegVariables == nil {
+			leg.LegVariables = make(map[string]string)
+		}
+		if _, ok := leg.LegVariables["origination_uuid"]; !ok {
+			if len(globalUUID) > 0 {
+				leg.LegVariables["origination_uuid"] = globalUUID
+			} else {
+				leg.LegVariables["origination_uuid"] = uuid.New().String()
+			}
+		}
+		legUUIDs = append(legUUIDs, leg.LegVariables["origination_uuid"])
+	}
+	return copied, legUUIDs, nil
+}
+
+// takeOriginationUUID - Removes origination_uuid from the variables, returning it or current when not set
+func takeOriginationUUID(vars map[string]string, current string) (string, error) {
+	value, ok := vars["origination_uuid"]
+	if !ok {
+		return current, nil
+	}
+	delete(vars, "origination_uuid")
+	if len(current) > 0 && current != value {
+		return current, errors.New("conflicting origination_uuid variables")
+	}
+	return value, nil
+}
+
+func copyVars(vars map[string]string) map[string]string {
+	if vars == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(vars))
+	for key, value := range vars {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,110 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/percipia/eslgo/command"
+	"github.com/percipia/eslgo/command/call"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConn_Originate(t *testing.T) {
+	conn, commands := dialTestServer(t, func(command string) string {
+		if strings.Contains(command, "user/busy") {
+			return testAPIResponse("-ERR USER_BUSY\n")
+		}
+		return testAPIResponse("+OK answered-uuid\n")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := conn.Originate(ctx, NewDialString(map[string]string{"origination_uuid": "fixed"}, LegGroup{UserLeg("1000", "")}), "&park()")
+	assert.Nil(t, err)
+	assert.Equal(t, "answered-uuid", result.UUID)
+	assert.Equal(t, []string{"fixed"}, result.LegUUIDs)
+
+	result, err = conn.Originate(ctx, NewDialString(nil, LegGroup{{CallURL: "user/busy"}}), "&park()")
+	var eslError *ESLError
+	assert.True(t, errors.As(err, &eslError))
+	assert.Equal(t, call.CauseUserBusy, result.Cause)
+	assert.Empty(t, result.UUID)
+	assert.Len(t, result.LegUUIDs, 1)
+
+	sent := commands()
+	assert.Equal(t, "api originate [origination_uuid=fixed]user/1000 &park()", sent[0])
+	assert.Equal(t, fmt.Sprintf("api originate [origination_uuid=%s]user/busy &park()", result.LegUUIDs[0]), sent[1])
+}
+
+func TestConn_OriginateBackground(t *testing.T) {
+	var jobUUID atomic.Value
+	conn, _ := dialTestServer(t, func(command string) string {
+		if strings.HasPrefix(command, "bgapi") {
+			return testReply("+OK Job-UUID: job")
+		}
+		// Any other command triggers the completion of the job
+		body := "-ERR NO_ANSWER\n"
+		event := fmt.Sprintf("Event-Name: BACKGROUND_JOB\nJob-UUID: %s\nContent-Length: %d\n\n%s", jobUUID.Load(), len(body), body)
+		return testAPIResponse("+OK\n") + fmt.Sprintf("Content-Length: %d\r\nContent-Type: text/event-plain\r\n\r\n%s", len(event), event)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	job, err := conn.OriginateBackground(ctx, NewDialString(nil, LegGroup{UserLeg("1000", ""), UserLeg("1001", "")}), "9999 XML default")
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Len(t, job.LegUUIDs, 2)
+	assert.NotEqual(t, job.LegUUIDs[0], job.LegUUIDs[1])
+	jobUUID.Store(job.JobUUID)
+
+	_, err = conn.SendCommand(ctx, command.API{Command: "status"})
+	assert.Nil(t, err)
+
+	result, err := job.Wait(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, call.CauseNoAnswer, result.Cause)
+	assert.Equal(t, job.LegUUIDs, result.LegUUIDs)
+}
+
+func TestWithOriginationUUIDs(t *testing.T) {
+	leg := UserLeg("1000", "")
+	leg.LegVariables = map[string]string{"origination_uuid": "leg"}
+	dialString := NewDialString(nil, LegGroup{leg, UserLeg("1001", "")})
+
+	prepared, legUUIDs, err := withOriginationUUIDs(dialString)
+	assert.Nil(t, err)
+	assert.Equal(t, "leg", legUUIDs[0])
+	assert.Equal(t, legUUIDs[1], prepared.Branches[0].Groups[0][1].LegVariables["origination_uuid"])
+	// The original dial string is left untouched
+	assert.Nil(t, dialString.Branches[0].Groups[0][1].LegVariables)
+
+	dialString.Variables = map[string]string{"origination_uuid": "global"}
+	_, _, err = withOriginationUUIDs(dialString)
+	assert.NotNil(t, err)
+	assert.Equal(t, "global", dialString.Variables["origination_uuid"])
+}
+
+func TestMoveOriginationUUID(t *testing.T) {
+	vars := map[string]string{"origination_uuid": "global", "a": "b"}
+	moved, leg := moveOriginationUUID(vars, UserLeg("1000", ""))
+	assert.Equal(t, map[string]string{"a": "b"}, moved)
+	assert.Equal(t, "global", leg.LegVariables["origination_uuid"])
+	assert.Equal(t, "global", vars["origination_uuid"])
+}
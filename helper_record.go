@@ -0,0 +1,287 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"github.com/percipia/eslgo/command"
+	"github.com/percipia/eslgo/command/call"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RecordOptions - Options for the recording helpers. Zero values leave the FreeSWITCH defaults and any channel variables already set untouched
+type RecordOptions struct {
+	Stereo         bool              // RECORD_STEREO, record each leg to its own channel
+	MinSeconds     int               // RECORD_MIN_SEC, recordings shorter than this are discarded
+	Append         bool              // RECORD_APPEND, append to the file if it already exists
+	FollowTransfer bool              // recording_follow_transfer, keep recording when the channel is transferred to another leg
+	FileFormat     string            // File extension FreeSWITCH uses to pick the format e.g. "wav" or "mp3", appended to the path when it has no extension
+	TimeLimit      time.Duration     // Stop recording after this long. Used by uuid_record and the record app
+	SilenceThresh  int               // Energy level treated as silence. Only used by the record app
+	SilenceHits    int               // How many consecutive silent frames end the recording. Only used by the record app
+	Variables      map[string]string // Additional channel variables to set before recording e.g. RECORD_TITLE
+}
+
+// RecordingResult - What FreeSWITCH reported in the RECORD_STOP event
+type RecordingResult struct {
+	Path            string        // The final path of the recording
+	Duration        time.Duration // The length of the recording, from record_ms or record_seconds
+	Samples         int64         // The size of the recording in samples, from record_samples
+	CompletionCause string        // Why the recording stopped e.g. success-silence, only reported by some methods
+}
+
+// Recording - A recording started by one of the recording helpers. Tracks the RECORD_START and RECORD_STOP events, requires events to be enabled!
+type Recording struct {
+	UUID string // The channel being recorded
+	Path string // The path passed to FreeSWITCH
+
+	conn    *Conn
+	method  string
+	started chan struct{}
+	stopped chan struct{}
+	result  *RecordingResult
+	err     error
+}
+
+// StartRecordSession - Executes the mod_dptools record_session app, recording the channel in the background
+func (c *Conn) StartRecordSession(ctx context.Context, uuid, filePath string, opts RecordOptions) (*Recording, error) {
+	filePath = recordingPath(filePath, opts)
+	return c.startRecording(ctx, uuid, filePath, "record_session", opts, &call.Execute{
+		UUID:    uuid,
+		AppName: "record_session",
+		AppArgs: filePath,
+	})
+}
+
+// StopRecordSession - Executes the mod_dptools stop_record_session app. filePath can be "all" to stop every recording on the channel
+func (c *Conn) StopRecordSession(ctx context.Context, uuid, filePath string) error {
+	_, err := c.SendCommandChecked(ctx, &call.Execute{
+		UUID:    uuid,
+		AppName: "stop_record_session",
+		AppArgs: filePath,
+	})
+	return err
+}
+
+// StartUUIDRecord - Starts recording the channel in the background using uuid_record
+func (c *Conn) StartUUIDRecord(ctx context.Context, uuid, filePath string, opts RecordOptions) (*Recording, error) {
+	filePath = recordingPath(filePath, opts)
+	args := fmt.Sprintf("%s start %s", uuid, filePath)
+	if opts.TimeLimit > 0 {
+		args += " " + strconv.Itoa(int(opts.TimeLimit.Seconds()))
+	}
+	return c.startRecording(ctx, uuid, filePath, "uuid_record", opts, command.API{
+		Command:   "uuid_record",
+		Arguments: args,
+	})
+}
+
+// StopUUIDRecord - Stops a recording using uuid_record. filePath can be "all" to stop every recording on the channel
+func (c *Conn) StopUUIDRecord(ctx context.Context, uuid, filePath string) error {
+	return c.uuidRecord(ctx, uuid, "stop", filePath)
+}
+
+// MaskRecording - Replaces the audio in the recording with silence until UnmaskRecording is called, e.g. while a card number is read out
+func (c *Conn) MaskRecording(ctx context.Context, uuid, filePath string) error {
+	return c.uuidRecord(ctx, uuid, "mask", filePath)
+}
+
+// UnmaskRecording - Resumes recording audio after MaskRecording
+func (c *Conn) UnmaskRecording(ctx context.Context, uuid, filePath string) error {
+	return c.uuidRecord(ctx, uuid, "unmask", filePath)
+}
+
+// Record - Executes the mod_dptools record app, recording what the caller says until the time limit, silence or a terminator digit
+// The app blocks the channel, use Recording.Wait to get the result once it completes
+func (c *Conn) Record(ctx context.Context, uuid, filePath string, opts RecordOptions) (*Recording, error) {
+	filePath = recordingPath(filePath, opts)
+	args := []string{filePath}
+	if opts.TimeLimit > 0 || opts.SilenceThresh > 0 || opts.SilenceHits > 0 {
+		args = append(args, strconv.Itoa(int(opts.TimeLimit.Seconds())))
+	}
+	if opts.SilenceThresh > 0 || opts.SilenceHits > 0 {
+		args = append(args, strconv.Itoa(opts.SilenceThresh), strconv.Itoa(opts.SilenceHits))
+	}
+	return c.startRecording(ctx, uuid, filePath, "record", opts, &call.Execute{
+		UUID:    uuid,
+		AppName: "record",
+		AppArgs: strings.Join(args, " "),
+	})
+}
+
+// Started - Closed once FreeSWITCH sent RECORD_START for the recording
+func (r *Recording) Started() <-chan struct{} {
+	return r.started
+}
+
+// Stopped - Closed once FreeSWITCH sent RECORD_STOP for the recording or the connection was closed
+func (r *Recording) Stopped() <-chan struct{} {
+	return r.stopped
+}
+
+// Wait - Waits for RECORD_STOP and returns what FreeSWITCH reported about the recording
+func (r *Recording) Wait(ctx context.Context) (*RecordingResult, error) {
+	select {
+	case <-r.stopped:
+		return r.result, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Stop - Stops the recording the same way it was started, records started with the record app are stopped using uuid_break
+func (r *Recording) Stop(ctx context.Context) error {
+	switch r.method {
+	case "record_session":
+		return r.conn.StopRecordSession(ctx, r.UUID, r.Path)
+	case "uuid_record":
+		return r.conn.StopUUIDRecord(ctx, r.UUID, r.Path)
+	}
+	_, err := r.conn.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_break",
+		Arguments: r.UUID,
+	})
+	return err
+}
+
+// Mask - Calls MaskRecording for the recording
+func (r *Recording) Mask(ctx context.Context) error {
+	return r.conn.MaskRecording(ctx, r.UUID, r.Path)
+}
+
+// Unmask - Calls UnmaskRecording for the recording
+func (r *Recording) Unmask(ctx context.Context) error {
+	return r.conn.UnmaskRecording(ctx, r.UUID, r.Path)
+}
+
+func (c *Conn) uuidRecord(ctx context.Context, uuid, action, filePath string) error {
+	_, err := c.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_record",
+		Arguments: fmt.Sprintf("%s %s %s", uuid, action, filePath),
+	})
+	return err
+}
+
+// startRecording - Sets the channel variables for the options, starts tracking the events and then sends the command starting the recording
+func (c *Conn) startRecording(ctx context.Context, uuid, filePath, method string, opts RecordOptions, cmd command.Command) (*Recording, error) {
+	for _, variable := range opts.channelVariables() {
+		_, err := c.SendCommandChecked(ctx, command.API{
+			Command:   "uuid_setvar",
+			Arguments: fmt.Sprintf("%s %s %s", uuid, variable[0], variable[1]),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	recording := &Recording{
+		UUID:    uuid,
+		Path:    filePath,
+		conn:    c,
+		method:  method,
+		started: make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	events := make(chan *Event, 2)
+	id := c.RegisterEventListener(uuid, func(event *Event) {
+		name := event.GetName()
+		if (name == "RECORD_START" || name == "RECORD_STOP") && recording.matches(event) {
+			select {
+			case events <- event:
+			default:
+			}
+		}
+	})
+	remove := func() {
+		c.RemoveEventListener(uuid, id)
+	}
+
+	if _, err := c.SendCommandChecked(ctx, cmd); err != nil {
+		remove()
+		return nil, err
+	}
+	go recording.track(events, remove)
+	return recording, nil
+}
+
+func (r *Recording) track(events <-chan *Event, remove func()) {
+	defer close(r.stopped)
+	defer remove()
+
+	startedOnce := false
+	for {
+		select {
+		case event := <-events:
+			if !startedOnce {
+				startedOnce = true
+				close(r.started)
+			}
+			if event.GetName() == "RECORD_STOP" {
+				r.result = recordingResult(event)
+				return
+			}
+		case <-r.conn.runningContext.Done():
+			r.err = ErrConnectionClosed
+			return
+		}
+	}
+}
+
+// matches - Returns true when the event is for this recording. Paths using channel variables are expanded by FreeSWITCH so any recording on the channel matches them
+func (r *Recording) matches(event *Event) bool {
+	return event.GetHeader("Record-File-Path") == r.Path || strings.Contains(r.Path, "${")
+}
+
+func (opts RecordOptions) channelVariables() [][2]string {
+	var vars [][2]string
+	if opts.Stereo {
+		vars = append(vars, [2]string{"RECORD_STEREO", "true"})
+	}
+	if opts.MinSeconds > 0 {
+		vars = append(vars, [2]string{"RECORD_MIN_SEC", strconv.Itoa(opts.MinSeconds)})
+	}
+	if opts.Append {
+		vars = append(vars, [2]string{"RECORD_APPEND", "true"})
+	}
+	if opts.FollowTransfer {
+		vars = append(vars, [2]string{"recording_follow_transfer", "true"})
+	}
+	for _, key := range sortedKeys(opts.Variables) {
+		vars = append(vars, [2]string{key, opts.Variables[key]})
+	}
+	return vars
+}
+
+// recordingPath - Adds the file format extension to the path if it does not have one
+func recordingPath(filePath string, opts RecordOptions) string {
+	if len(opts.FileFormat) == 0 || len(path.Ext(filePath)) > 0 {
+		return filePath
+	}
+	return filePath + "." + strings.TrimPrefix(opts.FileFormat, ".")
+}
+
+func recordingResult(event *Event) *RecordingResult {
+	result := &RecordingResult{
+		Path:            event.GetHeader("Record-File-Path"),
+		CompletionCause: event.GetHeader("Record-Completion-Cause"),
+	}
+	if ms, err := strconv.ParseInt(event.GetHeader("Variable_record_ms"), 10, 64); err == nil {
+		result.Duration = time.Duration(ms) * time.Millisecond
+	} else if seconds, err := strconv.ParseInt(event.GetHeader("Variable_record_seconds"), 10, 64); err == nil {
+		result.Duration = time.Duration(seconds) * time.Second
+	}
+	result.Samples, _ = strconv.ParseInt(event.GetHeader("Variable_record_samples"), 10, 64)
+	return result
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConn_StartUUIDRecord(t *testing.T) {
+	conn, commands := dialTestServer(t, func(command string) string {
+		if strings.HasPrefix(command, "api uuid_record channel start") {
+			return testAPIResponse("+OK Success\n") +
+				testEvent("Event-Name: RECORD_START", "Unique-ID: channel", "Record-File-Path: /tmp/call.wav") +
+				testEvent("Event-Name: RECORD_STOP", "Unique-ID: channel", "Record-File-Path: /tmp/other.wav") +
+				testEvent("Event-Name: RECORD_STOP", "Unique-ID: channel", "Record-File-Path: /tmp/call.wav",
+					"variable_record_ms: 61500", "variable_record_samples: 492000", "Record-Completion-Cause: success-maxtime")
+		}
+		return testAPIResponse("+OK\n")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	recording, err := conn.StartUUIDRecord(ctx, "channel", "/tmp/call", RecordOptions{
+		Stereo:         true,
+		FollowTransfer: true,
+		FileFormat:     "wav",
+		TimeLimit:      time.Minute,
+		Variables:      map[string]string{"RECORD_TITLE": "Support"},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	result, err := recording.Wait(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, &RecordingResult{
+		Path:            "/tmp/call.wav",
+		Duration:        61500 * time.Millisecond,
+		Samples:         492000,
+		CompletionCause: "success-maxtime",
+	}, result)
+	select {
+	case <-recording.Started():
+	default:
+		t.Error("RECORD_START was not tracked")
+	}
+
+	assert.Nil(t, recording.Mask(ctx))
+	assert.Equal(t, []string{
+		"api uuid_setvar channel RECORD_STEREO true",
+		"api uuid_setvar channel recording_follow_transfer true",
+		"api uuid_setvar channel RECORD_TITLE Support",
+		"api uuid_record channel start /tmp/call.wav 60",
+		"api uuid_record channel mask /tmp/call.wav",
+	}, commands())
+}
+
+func TestRecordingPath(t *testing.T) {
+	assert.Equal(t, "/tmp/call.mp3", recordingPath("/tmp/call", RecordOptions{FileFormat: ".mp3"}))
+	assert.Equal(t, "/tmp/call.wav", recordingPath("/tmp/call.wav", RecordOptions{FileFormat: "mp3"}))
+	assert.Equal(t, "/tmp/call", recordingPath("/tmp/call", RecordOptions{}))
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/percipia/eslgo/command/call"
+	"strings"
+)
+
+// SpeechGrammar - A grammar used for speech recognition. Path can be a file, a URL or an inline grammar such as builtin:grammar/boolean
+type SpeechGrammar struct {
+	Name string
+	Path string
+}
+
+// SpeakText - Executes the mod_dptools speak app, speaking the text with the TTS engine and voice
+func (c *Conn) SpeakText(ctx context.Context, uuid, engine, voice, text string, wait bool) (*RawResponse, error) {
+	return c.Speak(ctx, uuid, fmt.Sprintf("%s|%s|%s", engine, voice, text), 1, wait)
+}
+
+// DetectSpeech - Starts speech detection on the channel using the ASR engine with the grammar. Results are sent as DETECTED_SPEECH events
+func (c *Conn) DetectSpeech(ctx context.Context, uuid, engine string, grammar SpeechGrammar) error {
+	return c.detectSpeech(ctx, uuid, engine, grammar.Name, grammar.Path)
+}
+
+// LoadSpeechGrammar - Loads an additional grammar into a running speech detection
+func (c *Conn) LoadSpeechGrammar(ctx context.Context, uuid string, grammar SpeechGrammar) error {
+	return c.detectSpeech(ctx, uuid, "grammar", grammar.Name, grammar.Path)
+}
+
+// UnloadSpeechGrammar - Unloads a grammar from a running speech detection
+func (c *Conn) UnloadSpeechGrammar(ctx context.Context, uuid, name string) error {
+	return c.detectSpeech(ctx, uuid, "nogrammar", name)
+}
+
+// EnableSpeechGrammar - Enables a loaded grammar, or disables it when enabled is false
+func (c *Conn) EnableSpeechGrammar(ctx context.Context, uuid, name string, enabled bool) error {
+	if enabled {
+		return c.detectSpeech(ctx, uuid, "grammaron", name)
+	}
+	return c.detectSpeech(ctx, uuid, "grammaroff", name)
+}
+
+// PauseDetectSpeech - Pauses speech detection on the channel
+func (c *Conn) PauseDetectSpeech(ctx context.Context, uuid string) error {
+	return c.detectSpeech(ctx, uuid, "pause")
+}
+
+// ResumeDetectSpeech - Resumes paused speech detection on the channel
+func (c *Conn) ResumeDetectSpeech(ctx context.Context, uuid string) error {
+	return c.detectSpeech(ctx, uuid, "resume")
+}
+
+// StopDetectSpeech - Stops speech detection on the channel
+func (c *Conn) StopDetectSpeech(ctx context.Context, uuid string) error {
+	return c.detectSpeech(ctx, uuid, "stop")
+}
+
+// WaitForSpeech - Waits for the next DETECTED_SPEECH result on the channel. Requires events to be enabled!
+func (c *Conn) WaitForSpeech(ctx context.Context, uuid string) (*SpeechResult, error) {
+	events := make(chan *Event, 1)
+	id := c.RegisterEventListener(uuid, func(event *Event) {
+		if event.GetName() == "DETECTED_SPEECH" && event.GetHeader("Speech-Type") == "detected-speech" {
+			select {
+			case events <- event:
+			default:
+			}
+		}
+	})
+	defer c.RemoveEventListener(uuid, id)
+
+	select {
+	case event := <-events:
+		return event.SpeechResult()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// PlayAndDetectSpeech - Executes the mod_dptools play_and_detect_speech app, playing the file while listening for speech and waiting for the result
+// vars are passed to the ASR engine, contained in {}. Requires events to be enabled!
+func (c *Conn) PlayAndDetectSpeech(ctx context.Context, channelUUID, file, engine, grammar string, vars map[string]string) (*SpeechResult, error) {
+	appUUID := uuid.New().String()
+	events, remove := c.awaitEvent(appUUID, "CHANNEL_EXECUTE_COMPLETE")
+	defer remove()
+
+	_, err := c.SendCommandChecked(ctx, &call.Execute{
+		UUID:    channelUUID,
+		AppName: "play_and_detect_speech",
+		AppArgs: fmt.Sprintf("%s detect:%s %s%s", file, engine, BuildVars("{%s}", vars), grammar),
+		AppUUID: appUUID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case event := <-events:
+		result := event.GetHeader("Variable_detect_speech_result")
+		if len(strings.TrimSpace(result)) == 0 {
+			return nil, errors.New("no speech detected")
+		}
+		return ParseSpeechResult([]byte(result))
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c *Conn) detectSpeech(ctx context.Context, uuid string, args ...string) error {
+	_, err := c.SendCommandChecked(ctx, &call.Execute{
+		UUID:    uuid,
+		AppName: "detect_speech",
+		AppArgs: strings.Join(args, " "),
+	})
+	return err
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/percipia/eslgo/command"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Variable - The value of a channel variable with helpers to convert it
+type Variable string
+
+// Variables - Channel variables by name, as returned by DumpVars
+type Variables map[string]Variable
+
+// GetVar - Gets the channel variable using uuid_getvar. Returns ErrVariableNotSet if the variable is not set and an error matching ErrNoSuchChannel if the channel does not exist
+func (c *Conn) GetVar(ctx context.Context, uuid, name string) (Variable, error) {
+	response, err := c.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_getvar",
+		Arguments: fmt.Sprintf("%s %s", uuid, name),
+	})
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimRight(string(response.Body), "\r\n")
+	if value == "_undef_" {
+		return "", ErrVariableNotSet
+	}
+	return Variable(value), nil
+}
+
+// SetVar - Sets the channel variable using uuid_setvar
+func (c *Conn) SetVar(ctx context.Context, uuid, name, value string) error {
+	_, err := c.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_setvar",
+		Arguments: fmt.Sprintf("%s %s %s", uuid, name, value),
+	})
+	return err
+}
+
+// SetVars - Sets all the channel variables with a single uuid_setvar_multi, in order of the variable name
+func (c *Conn) SetVars(ctx context.Context, uuid string, vars map[string]string) error {
+	if len(vars) == 0 {
+		return nil
+	}
+	pairs := make([]string, 0, len(vars))
+	for _, name := range sortedKeys(vars) {
+		value := vars[name]
+		if strings.ContainsAny(value, ";'\\") {
+			value = quoteVar(value)
+		}
+		pairs = append(pairs, name+"="+value)
+	}
+	_, err := c.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_setvar_multi",
+		Arguments: fmt.Sprintf("%s %s", uuid, strings.Join(pairs, ";")),
+	})
+	return err
+}
+
+// UnsetVar - Unsets the channel variable using uuid_setvar without a value
+func (c *Conn) UnsetVar(ctx context.Context, uuid, name string) error {
+	_, err := c.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_setvar",
+		Arguments: fmt.Sprintf("%s %s", uuid, name),
+	})
+	return err
+}
+
+// DumpVars - Gets every channel variable using uuid_dump, the variable_ prefix FreeSWITCH adds is removed from the names
+func (c *Conn) DumpVars(ctx context.Context, uuid string) (Variables, error) {
+	response, err := c.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_dump",
+		Arguments: uuid + " json",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var dump map[string]interface{}
+	if err := json.Unmarshal(response.Body, &dump); err != nil {
+		return nil, fmt.Errorf("invalid uuid_dump reply: %w", err)
+	}
+	vars := make(Variables)
+	for key, value := range dump {
+		if name, ok := strings.CutPrefix(key, "variable_"); ok {
+			vars[name] = Variable(fmt.Sprint(value))
+		}
+	}
+	return vars, nil
+}
+
+// Get - Gets the variable, returns ErrVariableNotSet if it is not set
+func (v Variables) Get(name string) (Variable, error) {
+	value, ok := v[name]
+	if !ok {
+		return "", ErrVariableNotSet
+	}
+	return value, nil
+}
+
+// String - Returns the raw value
+func (v Variable) String() string {
+	return string(v)
+}
+
+// Int - Parses the value as an integer
+func (v Variable) Int() (int, error) {
+	return strconv.Atoi(strings.TrimSpace(string(v)))
+}
+
+// Bool - Parses the value the same way FreeSWITCH does, yes, on, true, t, enabled, active, allow and non zero numbers are true
+func (v Variable) Bool() bool {
+	value := strings.ToLower(strings.TrimSpace(string(v)))
+	switch value {
+	case "yes", "on", "true", "t", "enabled", "active", "allow":
+		return true
+	}
+	number, err := strconv.Atoi(value)
+	return err == nil && number != 0
+}
+
+// Duration - Parses the value as a duration. Plain numbers are seconds like billsec and duration, otherwise Go duration syntax is used e.g. 1m30s
+func (v Variable) Duration() (time.Duration, error) {
+	value := strings.TrimSpace(string(v))
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// Time - Parses the value as a time. Handles epoch variables in seconds or microseconds like start_epoch and start_uepoch, and
+// stamp variables like start_stamp which are in the local time of the FreeSWITCH server, assumed to be the same as ours
+func (v Variable) Time() (time.Time, error) {
+	value := strings.TrimSpace(string(v))
+	if epoch, err := strconv.ParseInt(value, 10, 64); err == nil {
+		// Microsecond epochs have 16 digits, second epochs 10 until the year 2286
+		if epoch > 1e12 {
+			return time.UnixMicro(epoch), nil
+		}
+		return time.Unix(epoch, 0), nil
+	}
+	parsed, err := time.ParseInLocation(time.DateTime, value, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("unsupported time format: " + value)
+	}
+	return parsed, nil
+}
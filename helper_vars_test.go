@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConn_Vars(t *testing.T) {
+	conn, commands := dialTestServer(t, func(command string) string {
+		switch {
+		case strings.HasPrefix(command, "api uuid_getvar missing"), strings.HasPrefix(command, "api uuid_dump missing"):
+			return testAPIResponse("-ERR No such channel!\n")
+		case command == "api uuid_getvar channel billsec":
+			return testAPIResponse("42")
+		case strings.HasPrefix(command, "api uuid_getvar"):
+			return testAPIResponse("_undef_")
+		case strings.HasPrefix(command, "api uuid_dump"):
+			return testAPIResponse(`{"Channel-State":"CS_EXECUTE","variable_start_epoch":"1700000000","variable_sip_from_user":"1000"}`)
+		}
+		return testAPIResponse("+OK\n")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	billsec, err := conn.GetVar(ctx, "channel", "billsec")
+	assert.Nil(t, err)
+	duration, err := billsec.Duration()
+	assert.Nil(t, err)
+	assert.Equal(t, 42*time.Second, duration)
+
+	_, err = conn.GetVar(ctx, "channel", "nothing")
+	assert.True(t, errors.Is(err, ErrVariableNotSet))
+	_, err = conn.GetVar(ctx, "missing", "billsec")
+	assert.True(t, errors.Is(err, ErrNoSuchChannel))
+
+	vars, err := conn.DumpVars(ctx, "channel")
+	assert.Nil(t, err)
+	assert.Equal(t, Variables{"start_epoch": "1700000000", "sip_from_user": "1000"}, vars)
+	_, err = conn.DumpVars(ctx, "missing")
+	assert.True(t, errors.Is(err, ErrNoSuchChannel))
+
+	assert.Nil(t, conn.SetVar(ctx, "channel", "greeting", "hello world"))
+	assert.Nil(t, conn.SetVars(ctx, "channel", map[string]string{"b": "x;y", "a": "1"}))
+	assert.Nil(t, conn.UnsetVar(ctx, "channel", "greeting"))
+
+	sent := commands()
+	assert.Equal(t, []string{
+		"api uuid_setvar channel greeting hello world",
+		"api uuid_setvar_multi channel a=1;b='x;y'",
+		"api uuid_setvar channel greeting",
+	}, sent[len(sent)-3:])
+}
+
+func TestVariable_Conversions(t *testing.T) {
+	number, err := Variable(" 12 ").Int()
+	assert.Nil(t, err)
+	assert.Equal(t, 12, number)
+	_, err = Variable("twelve").Int()
+	assert.NotNil(t, err)
+
+	for _, value := range []string{"true", "YES", "on", "1", "-1", "enabled"} {
+		assert.True(t, Variable(value).Bool(), value)
+	}
+	for _, value := range []string{"false", "no", "0", "", "maybe"} {
+		assert.False(t, Variable(value).Bool(), value)
+	}
+
+	duration, err := Variable("1m30s").Duration()
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Second, duration)
+
+	parsed, err := Variable("1700000000").Time()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Unix(1700000000, 0), parsed)
+	parsed, err = Variable("1700000000123456").Time()
+	assert.Nil(t, err)
+	assert.Equal(t, time.UnixMicro(1700000000123456), parsed)
+	parsed, err = Variable("2023-11-14 22:13:20").Time()
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2023, 11, 14, 22, 13, 20, 0, time.Local), parsed)
+	_, err = Variable("yesterday").Time()
+	assert.NotNil(t, err)
+}
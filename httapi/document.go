@@ -0,0 +1,233 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package httapi
+
+import (
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Document - The xml/freeswitch-httapi document telling FreeSWITCH what to do with the call next
+type Document struct {
+	Params    map[string]string // Sent back to us with every following request of the session
+	Variables map[string]string // Set as channel variables
+	Work      []Action          // Executed in order, the first action with an action URL or a break stops the work
+}
+
+// Action - A work action of the document, one of the action types in this package
+type Action interface {
+	httapiAction()
+}
+
+// Duration - A timeout, sent to FreeSWITCH in milliseconds
+type Duration time.Duration
+
+// Seconds - A limit FreeSWITCH expects in seconds, such as the record limit. Rounded down to whole seconds
+type Seconds time.Duration
+
+// Bind - A digit pattern ending input collection when matched, e.g. ~\d+# for any number of digits followed by #
+type Bind struct {
+	XMLName xml.Name `xml:"bind"`
+	Strip   string   `xml:"strip,attr,omitempty"` // Characters removed from the collected digits, e.g. #
+	Pattern string   `xml:",chardata"`
+}
+
+// Playback - Plays a file, optionally collecting digits or speech into the Name param of the next request
+type Playback struct {
+	XMLName      xml.Name `xml:"playback"`
+	File         string   `xml:"file,attr"`
+	Name         string   `xml:"name,attr,omitempty"`
+	ErrorFile    string   `xml:"error-file,attr,omitempty"`
+	Action       string   `xml:"action,attr,omitempty"` // The URL to request with the input, defaults to the current one
+	Loops        int      `xml:"loops,attr,omitempty"`
+	InputTimeout Duration `xml:"input-timeout,attr,omitempty"`
+	DigitTimeout Duration `xml:"digit-timeout,attr,omitempty"`
+	ASREngine    string   `xml:"asr-engine,attr,omitempty"`
+	ASRGrammar   string   `xml:"asr-grammar,attr,omitempty"`
+	Binds        []Bind   `xml:"bind"`
+}
+
+// Say - Says the text with the mod_say module of the language, optionally collecting digits like Playback
+type Say struct {
+	XMLName      xml.Name `xml:"say"`
+	Text         string   `xml:"text,attr"`
+	Language     string   `xml:"language,attr"`
+	Type         string   `xml:"type,attr"`   // e.g. number, items, currency or current_date_time
+	Method       string   `xml:"method,attr"` // e.g. pronounced, iterated or counted
+	Gender       string   `xml:"gender,attr,omitempty"`
+	Name         string   `xml:"name,attr,omitempty"`
+	Action       string   `xml:"action,attr,omitempty"`
+	InputTimeout Duration `xml:"input-timeout,attr,omitempty"`
+	DigitTimeout Duration `xml:"digit-timeout,attr,omitempty"`
+	Binds        []Bind   `xml:"bind"`
+}
+
+// Record - Records the caller to the file. When Name is set the recording is uploaded with the next request
+type Record struct {
+	XMLName      xml.Name `xml:"record"`
+	File         string   `xml:"file,attr"`
+	Name         string   `xml:"name,attr,omitempty"`
+	ErrorFile    string   `xml:"error-file,attr,omitempty"`
+	BeepFile     string   `xml:"beep-file,attr,omitempty"`
+	Action       string   `xml:"action,attr,omitempty"`
+	Limit        Seconds  `xml:"limit,attr,omitempty"`
+	DigitTimeout Duration `xml:"digit-timeout,attr,omitempty"`
+	Terminators  string   `xml:"terminators,attr,omitempty"`
+	Binds        []Bind   `xml:"bind"`
+}
+
+// GetVariable - Sends the channel variable back as a param of the next request
+type GetVariable struct {
+	XMLName   xml.Name `xml:"getVariable"`
+	Name      string   `xml:"name,attr"`
+	Permanent bool     `xml:"permanent,attr,omitempty"` // Send the variable with every following request instead of only the next one
+}
+
+// Execute - Executes a dialplan application
+type Execute struct {
+	XMLName     xml.Name `xml:"execute"`
+	Application string   `xml:"application,attr"`
+	Data        string   `xml:"data,attr,omitempty"`
+	Action      string   `xml:"action,attr,omitempty"`
+}
+
+// Hangup - Hangs up the call
+type Hangup struct {
+	XMLName xml.Name `xml:"hangup"`
+	Cause   string   `xml:"cause,attr,omitempty"` // e.g. NORMAL_CLEARING, see call.HangupCause
+	Action  string   `xml:"action,attr,omitempty"`
+}
+
+// Break - Stops the work and ends the session without requesting another document
+type Break struct {
+	XMLName xml.Name `xml:"break"`
+}
+
+// Continue - Stops the work and continues the call in the dialplan
+type Continue struct {
+	XMLName xml.Name `xml:"continue"`
+}
+
+// Voicemail - Sends the caller to the voicemail box, or lets them check it
+type Voicemail struct {
+	XMLName  xml.Name `xml:"voicemail"`
+	Profile  string   `xml:"profile,attr,omitempty"` // Defaults to default
+	Domain   string   `xml:"domain,attr,omitempty"`
+	ID       string   `xml:"id,attr,omitempty"`
+	Check    bool     `xml:"check,attr,omitempty"`
+	AuthOnly bool     `xml:"auth-only,attr,omitempty"`
+	Action   string   `xml:"action,attr,omitempty"`
+}
+
+func (Playback) httapiAction()    {}
+func (Say) httapiAction()         {}
+func (Record) httapiAction()      {}
+func (GetVariable) httapiAction() {}
+func (Execute) httapiAction()     {}
+func (Hangup) httapiAction()      {}
+func (Break) httapiAction()       {}
+func (Continue) httapiAction()    {}
+func (Voicemail) httapiAction()   {}
+
+// NewDocument - Creates a document executing the actions
+func NewDocument(actions ...Action) *Document {
+	return &Document{Work: actions}
+}
+
+// Add - Adds the actions to the work of the document
+func (d *Document) Add(actions ...Action) *Document {
+	d.Work = append(d.Work, actions...)
+	return d
+}
+
+// SetParam - Sets a param sent back with every following request of the session
+func (d *Document) SetParam(name, value string) *Document {
+	if d.Params == nil {
+		d.Params = make(map[string]string)
+	}
+	d.Params[name] = value
+	return d
+}
+
+// SetVariable - Sets a channel variable
+func (d *Document) SetVariable(name, value string) *Document {
+	if d.Variables == nil {
+		d.Variables = make(map[string]string)
+	}
+	d.Variables[name] = value
+	return d
+}
+
+// String - Builds the XML of the document
+func (d Document) String() string {
+	data, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		// Only happens with param or variable names that are not valid XML names
+		return ""
+	}
+	return string(data)
+}
+
+// MarshalXML - Implements xml.Marshaler. Params and variables are elements named after them, sorted by name
+func (d Document) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	start = xml.StartElement{
+		Name: xml.Name{Local: "document"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "type"}, Value: "xml/freeswitch-httapi"}},
+	}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := encodeValues(encoder, "params", d.Params); err != nil {
+		return err
+	}
+	if err := encodeValues(encoder, "variables", d.Variables); err != nil {
+		return err
+	}
+	if err := encoder.EncodeElement(struct {
+		Work []Action
+	}{d.Work}, xml.StartElement{Name: xml.Name{Local: "work"}}); err != nil {
+		return err
+	}
+	return encoder.EncodeToken(start.End())
+}
+
+// MarshalXMLAttr - Implements xml.MarshalerAttr, FreeSWITCH expects milliseconds
+func (d Duration) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: strconv.FormatInt(time.Duration(d).Milliseconds(), 10)}, nil
+}
+
+// MarshalXMLAttr - Implements xml.MarshalerAttr, FreeSWITCH expects seconds
+func (s Seconds) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	return xml.Attr{Name: name, Value: strconv.FormatInt(int64(time.Duration(s).Seconds()), 10)}, nil
+}
+
+func encodeValues(encoder *xml.Encoder, name string, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := encoder.EncodeToken(start); err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := encoder.EncodeElement(values[key], xml.StartElement{Name: xml.Name{Local: key}}); err != nil {
+			return err
+		}
+	}
+	return encoder.EncodeToken(start.End())
+}
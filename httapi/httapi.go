@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package httapi
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// maxUploadMemory - Recordings larger than this are stored in temporary files while handling the request
+const maxUploadMemory = 32 << 20
+
+// Handler - Returns the next document for the session. Requests with Exiting set are sent when the call is over, the document is then ignored
+type Handler func(ctx context.Context, request *Request) (*Document, error)
+
+// Request - A mod_httapi request. The other posted fields, including the channel variables and params, are accessed like Event headers
+type Request struct {
+	SessionID string // Identifies the call across requests, the same for every request of the session
+	Exiting   bool   // The session is over, FreeSWITCH does not use the returned document
+	Hostname  string // The hostname of the FreeSWITCH server
+	Headers   textproto.MIMEHeader
+	Files     map[string][]*multipart.FileHeader // Recordings uploaded by a Record action with a name, by name
+}
+
+// Server - An http.Handler answering mod_httapi requests with the documents returned by Handler
+type Server struct {
+	Handler Handler
+	Logger  *slog.Logger // Optional, handler errors are logged here
+}
+
+// ParseRequest - Decodes the form mod_httapi posts, including uploaded recordings
+func ParseRequest(r *http.Request) (*Request, error) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+			return nil, err
+		}
+	} else if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	request := &Request{
+		SessionID: r.Form.Get("session_id"),
+		Exiting:   r.Form.Get("exiting") == "true",
+		Hostname:  r.Form.Get("hostname"),
+		Headers:   make(textproto.MIMEHeader),
+	}
+	for key, values := range r.Form {
+		for _, value := range values {
+			request.Headers.Add(key, value)
+		}
+	}
+	if r.MultipartForm != nil {
+		request.Files = r.MultipartForm.File
+	}
+	return request, nil
+}
+
+// HasHeader Helper to check if the Request has a header
+func (r Request) HasHeader(header string) bool {
+	_, ok := r.Headers[textproto.CanonicalMIMEHeaderKey(header)]
+	return ok
+}
+
+// GetHeader Helper function that calls r.Header.Get
+func (r Request) GetHeader(header string) string {
+	return r.Headers.Get(header)
+}
+
+// GetVariable Helper to get a channel variable, without the variable_ prefix
+func (r Request) GetVariable(name string) string {
+	return r.GetHeader("variable_" + name)
+}
+
+// File - Opens the recording uploaded with the name, returns http.ErrMissingFile if there is none
+func (r Request) File(name string) (multipart.File, error) {
+	files := r.Files[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0].Open()
+}
+
+// ServeHTTP - Implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request, err := ParseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.Handler == nil {
+		err = errors.New("no handler")
+	}
+
+	var doc *Document
+	if err == nil {
+		doc, err = s.Handler(r.Context(), request)
+	}
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.Error("Error handling mod_httapi request", "session_id", request.SessionID, "error", err)
+		}
+		// FreeSWITCH ends the session when it cannot get a document
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	if doc == nil {
+		doc = &Document{}
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = w.Write([]byte(xml.Header))
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	_ = encoder.Encode(doc)
+}
@@ -0,0 +1,149 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package httapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocument_String(t *testing.T) {
+	doc := NewDocument(
+		Playback{
+			File:         "/tmp/menu.wav",
+			Name:         "choice",
+			InputTimeout: Duration(5 * time.Second),
+			Binds:        []Bind{{Pattern: "~\\d"}},
+		},
+		Say{Text: "123", Language: "en", Type: "number", Method: "pronounced"},
+		Record{File: "/tmp/message.wav", Name: "message", Limit: Seconds(time.Minute), Binds: []Bind{{Strip: "#", Pattern: "#"}}},
+		GetVariable{Name: "caller_id_number", Permanent: true},
+		Execute{Application: "answer"},
+		Voicemail{Domain: "example.com", ID: "1000", Check: true},
+		Hangup{Cause: "NORMAL_CLEARING"},
+		Break{},
+		Continue{},
+	).SetParam("step", "menu").SetVariable("ivr_lang", "en")
+
+	assert.Equal(t, `<document type="xml/freeswitch-httapi">
+  <params>
+    <step>menu</step>
+  </params>
+  <variables>
+    <ivr_lang>en</ivr_lang>
+  </variables>
+  <work>
+    <playback file="/tmp/menu.wav" name="choice" input-timeout="5000">
+      <bind>~\d</bind>
+    </playback>
+    <say text="123" language="en" type="number" method="pronounced"></say>
+    <record file="/tmp/message.wav" name="message" limit="60">
+      <bind strip="#">#</bind>
+    </record>
+    <getVariable name="caller_id_number" permanent="true"></getVariable>
+    <execute application="answer"></execute>
+    <voicemail domain="example.com" id="1000" check="true"></voicemail>
+    <hangup cause="NORMAL_CLEARING"></hangup>
+    <break></break>
+    <continue></continue>
+  </work>
+</document>`, doc.String())
+}
+
+func TestServer_ServeHTTP(t *testing.T) {
+	server := &Server{
+		Handler: func(ctx context.Context, request *Request) (*Document, error) {
+			if request.Exiting {
+				return nil, nil
+			}
+			switch request.GetHeader("step") {
+			case "":
+				assert.Equal(t, "1000", request.GetVariable("caller_id_number"))
+				return NewDocument(Playback{File: "/tmp/menu.wav", Name: "choice"}).SetParam("step", "menu"), nil
+			case "menu":
+				return NewDocument(Hangup{}), nil
+			}
+			return nil, errors.New("unknown step")
+		},
+	}
+	post := func(form url.Values) (int, string) {
+		request := httptest.NewRequest(http.MethodPost, "/ivr", strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, request)
+		return recorder.Code, recorder.Body.String()
+	}
+
+	code, body := post(url.Values{"session_id": {"session"}, "variable_caller_id_number": {"1000"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<document type="xml/freeswitch-httapi">
+  <params>
+    <step>menu</step>
+  </params>
+  <work>
+    <playback file="/tmp/menu.wav" name="choice"></playback>
+  </work>
+</document>`, body)
+
+	code, body = post(url.Values{"session_id": {"session"}, "step": {"menu"}, "choice": {"1"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "<hangup></hangup>")
+
+	code, body = post(url.Values{"session_id": {"session"}, "exiting": {"true"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `<document type="xml/freeswitch-httapi">`)
+
+	code, _ = post(url.Values{"session_id": {"session"}, "step": {"other"}})
+	assert.Equal(t, http.StatusInternalServerError, code)
+}
+
+func TestParseRequest_Upload(t *testing.T) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	assert.Nil(t, writer.WriteField("session_id", "session"))
+	part, err := writer.CreateFormFile("message", "message.wav")
+	assert.Nil(t, err)
+	_, _ = part.Write([]byte("RIFF"))
+	assert.Nil(t, writer.Close())
+
+	r := httptest.NewRequest(http.MethodPost, "/ivr", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	request, err := ParseRequest(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "session", request.SessionID)
+
+	file, err := request.File("message")
+	if assert.Nil(t, err) {
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "RIFF", string(data))
+		_ = file.Close()
+	}
+	_, err = request.File("other")
+	assert.Equal(t, http.ErrMissingFile, err)
+}
+
+func TestRecord_Units(t *testing.T) {
+	data, err := xml.Marshal(Record{File: "/tmp/message.wav", Limit: Seconds(90 * time.Second), DigitTimeout: Duration(1500 * time.Millisecond)})
+	assert.Nil(t, err)
+	assert.Equal(t, `<record file="/tmp/message.wav" limit="90" digit-timeout="1500"></record>`, string(data))
+}
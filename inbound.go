@@ -12,19 +12,25 @@ package eslgo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/percipia/eslgo/command"
 	"net"
+	"strings"
+	"sync"
 	"time"
 )
 
 // InboundOptions - Used to dial a new inbound ESL connection to FreeSWITCH
 type InboundOptions struct {
-	Options                    // Generic common options to both Inbound and Outbound Conn
-	Network      string        // The network type to use, should always be tcp, tcp4, tcp6.
-	Password     string        // The password used to authenticate with FreeSWITCH. Usually ClueCon
-	OnDisconnect func()        // An optional function to be called with the inbound connection gets disconnected
-	AuthTimeout  time.Duration // How long to wait for authentication to complete
+	Options                      // Generic common options to both Inbound and Outbound Conn
+	Network      string          // The network type to use, should always be tcp, tcp4, tcp6.
+	Password     string          // The password used to authenticate with FreeSWITCH. Usually ClueCon
+	User         string          // Optional directory user to log in as with userauth, e.g. 1000@example.com. Password is then the password of the user
+	OnDisconnect func()          // An optional function to be called with the inbound connection gets disconnected
+	AuthTimeout  time.Duration   // How long to wait for authentication to complete
+	KeepAlive    time.Duration   // TCP keepalive period for the connection. Zero uses the Go default of 15 seconds, negative disables keepalive
+	Liveness     LivenessOptions // Optional monitoring to tear down connections that silently died. OnDisconnect is called when a dead connection is detected
 }
 
 // DefaultOutboundOptions - The default options used for creating the inbound connection
@@ -44,39 +50,86 @@ func Dial(address, password string, onDisconnect func()) (*Conn, error) {
 }
 
 // Dial - Connects to FreeSWITCH ESL on the address with the provided options. Returns the connection and any errors encountered
+// ErrACLRejected is returned when FreeSWITCH does not allow connections from our address
 func (opts InboundOptions) Dial(address string) (*Conn, error) {
-	c, err := net.Dial(opts.Network, address)
+	dialer := net.Dialer{KeepAlive: opts.KeepAlive}
+	c, err := dialer.Dial(opts.Network, address)
 	if err != nil {
 		return nil, err
 	}
 	connection := newConnection(c, false, opts.Options)
 
+	// Liveness monitoring and FreeSWITCH can both report the disconnect, only tell the user once
+	onDisconnect := opts.OnDisconnect
+	if onDisconnect != nil {
+		onDisconnect = sync.OnceFunc(onDisconnect)
+	}
+
 	// First auth
-	<-connection.responseChannels[TypeAuthRequest]
 	authCtx, cancel := context.WithTimeout(connection.runningContext, opts.AuthTimeout)
-	err = connection.doAuth(authCtx, command.Auth{Password: opts.Password})
+	err = connection.awaitAuthRequest(authCtx)
+	if err == nil {
+		err = connection.doAuth(authCtx, command.Auth{User: opts.User, Password: opts.Password})
+	}
 	cancel()
-	if err != nil {
+	if errors.Is(err, ErrACLRejected) {
+		// FreeSWITCH already hung up on us, there is no one to say goodbye to
+		connection.Close()
+		if onDisconnect != nil {
+			go onDisconnect()
+		}
+		return nil, err
+	} else if err != nil {
 		// Try to gracefully disconnect, we have the wrong password.
 		connection.ExitAndClose()
-		if opts.OnDisconnect != nil {
-			go opts.OnDisconnect()
+		if onDisconnect != nil {
+			go onDisconnect()
 		}
 		return nil, err
 	} else {
-		connection.logger.Info("Successfully authenticated %s\n", connection.conn.RemoteAddr())
+		connection.log().Info("Successfully authenticated")
+	}
+
+	livenessCtx, cancel := context.WithTimeout(connection.runningContext, opts.AuthTimeout)
+	err = connection.startLiveness(livenessCtx, opts.Liveness, onDisconnect)
+	cancel()
+	if err != nil {
+		connection.ExitAndClose()
+		if onDisconnect != nil {
+			go onDisconnect()
+		}
+		return nil, err
 	}
 
 	// Inbound only handlers
-	go connection.authLoop(command.Auth{Password: opts.Password}, opts.AuthTimeout)
-	go connection.disconnectLoop(opts.OnDisconnect)
+	go connection.authLoop(command.Auth{User: opts.User, Password: opts.Password}, opts.AuthTimeout)
+	go connection.disconnectLoop(onDisconnect)
 
 	return connection, nil
 }
 
+// awaitAuthRequest - Waits for FreeSWITCH to ask us to authenticate. Returns ErrACLRejected if FreeSWITCH rejected us instead
+func (c *Conn) awaitAuthRequest(ctx context.Context) error {
+	select {
+	case <-c.responseChannel(TypeAuthRequest):
+		return nil
+	case rejection := <-c.responseChannel(TypeRudeRejection):
+		if rejection == nil {
+			return ErrConnectionClosed
+		}
+		return fmt.Errorf("%w: %s", ErrACLRejected, strings.TrimSpace(string(rejection.Body)))
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for auth request: %w", ctx.Err())
+	}
+}
+
 func (c *Conn) disconnectLoop(onDisconnect func()) {
 	select {
-	case <-c.responseChannels[TypeDisconnect]:
+	case _, ok := <-c.responseChannel(TypeDisconnect):
+		if !ok {
+			// Closed by us
+			return
+		}
 		c.Close()
 		if onDisconnect != nil {
 			onDisconnect()
@@ -88,19 +141,23 @@ func (c *Conn) disconnectLoop(onDisconnect func()) {
 }
 
 func (c *Conn) authLoop(auth command.Auth, authTimeout time.Duration) {
+	authRequests := c.responseChannel(TypeAuthRequest)
 	for {
 		select {
-		case <-c.responseChannels[TypeAuthRequest]:
+		case _, ok := <-authRequests:
+			if !ok {
+				return
+			}
 			authCtx, cancel := context.WithTimeout(c.runningContext, authTimeout)
 			err := c.doAuth(authCtx, auth)
 			cancel()
 			if err != nil {
-				c.logger.Warn("Failed to auth %e\n", err)
+				c.log().Warn("Failed to auth", LogKeyCommand, commandName(auth.BuildMessage()), LogKeyError, err)
 				// Close the connection, we have the wrong password
 				c.ExitAndClose()
 				return
 			} else {
-				c.logger.Info("Successfully authenticated %s\n", c.conn.RemoteAddr())
+				c.log().Info("Successfully authenticated")
 			}
 		case <-c.runningContext.Done():
 			return
@@ -113,8 +170,11 @@ func (c *Conn) doAuth(ctx context.Context, auth command.Auth) error {
 	if err != nil {
 		return err
 	}
+	if err = response.Err(auth); err != nil {
+		return err
+	}
 	if !response.IsOk() {
-		return fmt.Errorf("failed to auth %#v", response)
+		return fmt.Errorf("%w: %s", ErrAuthFailed, response.GetReply())
 	}
 	return nil
 }
@@ -0,0 +1,298 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"github.com/percipia/eslgo/command"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer - A minimal fake FreeSWITCH inbound ESL server. respond is called with every command received and returns the raw data to write back, if any
+type testServer struct {
+	listener net.Listener
+	respond  func(command string) string
+}
+
+func newTestServer(t *testing.T, respond func(command string) string) *testServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &testServer{listener: listener, respond: respond}
+	go server.serve()
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	return server
+}
+
+func (s *testServer) Address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *testServer) serve() {
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(c)
+	}
+}
+
+func (s *testServer) handle(c net.Conn) {
+	defer c.Close()
+	reader := bufio.NewReader(c)
+	header := textproto.NewReader(reader)
+	_, _ = c.Write([]byte("Content-Type: auth/request\r\n\r\n"))
+	for {
+		line, err := header.ReadLine()
+		if err != nil {
+			return
+		}
+		// Read and discard any headers and body sent with the command
+		headers, err := header.ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		if length, _ := strconv.Atoi(headers.Get("Content-Length")); length > 0 {
+			if _, err = io.CopyN(io.Discard, reader, int64(length)); err != nil {
+				return
+			}
+		}
+		if response := s.respond(strings.TrimSpace(line)); len(response) > 0 {
+			_, _ = c.Write([]byte(response))
+		}
+	}
+}
+
+// testReply - Formats a command/reply message
+func testReply(reply string) string {
+	return "Content-Type: command/reply\r\nReply-Text: " + reply + "\r\n\r\n"
+}
+
+func TestInboundOptions_Dial_Liveness(t *testing.T) {
+	var lock sync.Mutex
+	var commands []string
+	server := newTestServer(t, func(command string) string {
+		lock.Lock()
+		defer lock.Unlock()
+		commands = append(commands, command)
+		if strings.HasPrefix(command, "auth") || strings.HasPrefix(command, "event") {
+			return testReply("+OK")
+		}
+		// Simulate a dead connection by never answering anything else
+		return ""
+	})
+
+	disconnected := make(chan struct{})
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	opts.OnDisconnect = func() {
+		close(disconnected)
+	}
+	opts.Liveness = LivenessOptions{
+		Mode:      LivenessHeartbeat,
+		Interval:  20 * time.Millisecond,
+		Threshold: 2,
+	}
+	conn, err := opts.Dial(server.Address())
+	assert.Nil(t, err)
+	assert.NotNil(t, conn)
+
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead connection was not detected")
+	}
+	assert.Error(t, conn.runningContext.Err())
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{"auth ClueCon", "event plain HEARTBEAT"}, commands)
+}
+
+func TestInboundOptions_Dial_LivenessHeartbeatFilter(t *testing.T) {
+	var lock sync.Mutex
+	var commands []string
+	server := newTestServer(t, func(command string) string {
+		lock.Lock()
+		defer lock.Unlock()
+		commands = append(commands, command)
+		return testReply("+OK")
+	})
+
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	opts.Liveness = LivenessOptions{Mode: LivenessHeartbeat, Interval: time.Minute}
+	conn, err := opts.Dial(server.Address())
+	assert.Nil(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	userFilter := command.Filter{EventHeader: "Unique-ID", FilterValue: "channel"}
+	for _, filter := range []command.Filter{userFilter, {EventHeader: "Caller-Context", FilterValue: "default"}, {Delete: true, EventHeader: "all"}} {
+		_, err = conn.SendCommand(ctx, filter)
+		assert.Nil(t, err)
+		if len(conn.Filters()) > 0 {
+			assert.Contains(t, conn.Filters(), heartbeatFilter)
+		}
+	}
+	_, err = conn.SendCommand(ctx, userFilter)
+	assert.Nil(t, err)
+	_, err = conn.SendCommand(ctx, command.Filter{Delete: true, EventHeader: "Unique-ID"})
+	assert.Nil(t, err)
+	assert.Empty(t, conn.Filters())
+
+	lock.Lock()
+	defer lock.Unlock()
+	assert.Equal(t, []string{
+		"auth ClueCon",
+		"event plain HEARTBEAT",
+		"filter Unique-ID channel",
+		"filter Event-Name HEARTBEAT",
+		"filter Caller-Context default",
+		"filter delete all",
+		"filter Unique-ID channel",
+		"filter Event-Name HEARTBEAT",
+		"filter delete Unique-ID",
+		"filter delete Event-Name HEARTBEAT",
+	}, commands)
+}
+
+func TestInboundOptions_Dial_LivenessProbeTimeout(t *testing.T) {
+	server := newTestServer(t, func(command string) string {
+		if strings.HasPrefix(command, "auth") {
+			return testReply("+OK")
+		}
+		// Never answer the probe
+		return ""
+	})
+
+	disconnected := make(chan struct{})
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	opts.OnDisconnect = func() {
+		close(disconnected)
+	}
+	// The threshold alone would take 2 seconds, a probe that times out must close the connection right away
+	opts.Liveness = LivenessOptions{
+		Mode:      LivenessProbe,
+		Interval:  20 * time.Millisecond,
+		Threshold: 100,
+	}
+	conn, err := opts.Dial(server.Address())
+	assert.Nil(t, err)
+
+	select {
+	case <-disconnected:
+	case <-time.After(time.Second):
+		t.Fatal("unanswered probe did not close the connection")
+	}
+	assert.Error(t, conn.runningContext.Err())
+}
+
+func TestInboundOptions_Dial_LivenessStuckCommand(t *testing.T) {
+	server := newTestServer(t, func(command string) string {
+		if strings.HasPrefix(command, "auth") {
+			return testReply("+OK")
+		}
+		// Simulate a half-open socket, nothing is answered
+		return ""
+	})
+
+	disconnected := make(chan struct{})
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	opts.OnDisconnect = func() {
+		close(disconnected)
+	}
+	opts.Liveness = LivenessOptions{
+		Mode:      LivenessProbe,
+		Interval:  20 * time.Millisecond,
+		Threshold: 3,
+	}
+	conn, err := opts.Dial(server.Address())
+	assert.Nil(t, err)
+
+	// A command without a deadline holds the connection, the probes cannot be sent but the dead link must still be detected
+	stuck := make(chan error, 1)
+	go func() {
+		_, err := conn.SendCommand(context.Background(), command.API{Command: "uptime"})
+		stuck <- err
+	}()
+
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("dead connection was not detected while a command was stuck")
+	}
+	assert.ErrorIs(t, <-stuck, ErrConnectionClosed)
+}
+
+func TestInboundOptions_Dial_UserAuth(t *testing.T) {
+	server := newTestServer(t, func(command string) string {
+		if command == "userauth 1000@example.com:secret" {
+			return testReply("+OK accepted")
+		}
+		return testReply("-ERR invalid")
+	})
+
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	opts.ExitTimeout = 100 * time.Millisecond
+	opts.User = "1000@example.com"
+	opts.Password = "secret"
+	conn, err := opts.Dial(server.Address())
+	if assert.Nil(t, err) {
+		conn.Close()
+	}
+
+	opts.Password = "wrong"
+	_, err = opts.Dial(server.Address())
+	assert.True(t, errors.Is(err, ErrAuthFailed))
+}
+
+func TestInboundOptions_Dial_ACLRejected(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		c, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		_, _ = c.Write([]byte("Content-Type: text/rude-rejection\r\nContent-Length: 24\r\n\r\nAccess Denied, go away.\n"))
+	}()
+
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	opts.AuthTimeout = 5 * time.Second
+	started := time.Now()
+	_, err = opts.Dial(listener.Addr().String())
+	assert.True(t, errors.Is(err, ErrACLRejected))
+	assert.Contains(t, err.Error(), "Access Denied, go away.")
+	assert.Less(t, time.Since(started), opts.AuthTimeout)
+}
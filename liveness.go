@@ -0,0 +1,171 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"errors"
+	"github.com/percipia/eslgo/command"
+	"time"
+)
+
+type LivenessMode int
+
+const (
+	// LivenessDisabled - Do not monitor the connection, a silently dead TCP link will go unnoticed
+	LivenessDisabled LivenessMode = iota
+	// LivenessHeartbeat - Subscribe to HEARTBEAT events and consider the connection dead when nothing is received for too long
+	// Once filters are added a "filter Event-Name HEARTBEAT" is added along with them so the HEARTBEAT events keep coming. Sending
+	// nixevent HEARTBEAT or noevents stops them though and the connection will be closed, use LivenessProbe when doing so
+	LivenessHeartbeat
+	// LivenessProbe - Periodically send "api status" and consider the connection dead when it is not answered
+	// A probe that is not answered within the interval closes the connection right away, its late reply would otherwise be taken as the reply to the next command
+	// While another command waits for its reply the probe is skipped, the connection is then closed when nothing is received for Threshold intervals
+	LivenessProbe
+)
+
+// LivenessOptions - Used to detect inbound connections that died without FreeSWITCH or the OS telling us, e.g. NAT timeouts or half-open sockets
+type LivenessOptions struct {
+	Mode      LivenessMode  // How the connection is monitored, disabled by default
+	Interval  time.Duration // How often FreeSWITCH sends HEARTBEAT events or how often we probe. Defaults to 20 seconds, the FreeSWITCH heartbeat default
+	Threshold int           // How many intervals in a row can pass without receiving anything before the connection is torn down. Defaults to 3
+}
+
+func (l LivenessOptions) withDefaults() LivenessOptions {
+	if l.Interval <= 0 {
+		l.Interval = 20 * time.Second
+	}
+	if l.Threshold <= 0 {
+		l.Threshold = 3
+	}
+	return l
+}
+
+// startLiveness - Starts monitoring the connection, onDead is called after the connection has been closed because it was considered dead
+func (c *Conn) startLiveness(ctx context.Context, opts LivenessOptions, onDead func()) error {
+	if opts.Mode == LivenessDisabled {
+		return nil
+	}
+	opts = opts.withDefaults()
+
+	if opts.Mode == LivenessHeartbeat {
+		c.heartbeatLiveness = true
+		// Event subscriptions are additive so this does not interfere with the events the user subscribes to
+		_, err := c.SendCommand(ctx, command.Event{
+			Format: "plain",
+			Listen: []string{"HEARTBEAT"},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	c.markReceived()
+	go c.livenessLoop(opts, onDead)
+	return nil
+}
+
+func (c *Conn) livenessLoop(opts LivenessOptions, onDead func()) {
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.runningContext.Done():
+			return
+		}
+
+		if opts.Mode == LivenessProbe {
+			ctx, cancel := context.WithTimeout(c.runningContext, opts.Interval)
+			_, err := c.SendCommand(ctx, command.API{Command: "status"})
+			cancel()
+			if errors.Is(err, errWriteBusy) {
+				// Another command is waiting for its reply, the probe was never sent. Whether anything was received still tells if the link is dead
+				c.log().Debug("Liveness probe not sent, waiting for another command", LogKeyError, err)
+			} else if errors.Is(err, context.DeadlineExceeded) && c.runningContext.Err() == nil {
+				// The reply may still arrive and would be handed to the next command, so the connection cannot be used any more
+				c.log().Warn("Connection considered dead, liveness probe was not answered", "interval", opts.Interval)
+				c.closeDead(onDead)
+				return
+			} else if err != nil {
+				c.log().Debug("Liveness probe failed", LogKeyError, err)
+			}
+		}
+
+		if time.Since(c.lastReceivedTime()) < opts.Interval {
+			missed = 0
+			continue
+		}
+		missed++
+		c.log().Debug("Liveness interval missed", "missed", missed, "threshold", opts.Threshold)
+		if missed >= opts.Threshold {
+			c.log().Warn("Connection considered dead, nothing received from FreeSWITCH", "missed", missed, "interval", opts.Interval)
+			c.closeDead(onDead)
+			return
+		}
+	}
+}
+
+// heartbeatFilter - Lets HEARTBEAT events through once the user filters events
+var heartbeatFilter = command.Filter{EventHeader: "Event-Name", FilterValue: "HEARTBEAT"}
+
+// keepHeartbeatFilter - Adds the heartbeat filter when the user added filters, which would drop the HEARTBEAT events otherwise, and deletes
+// it again once the user filters are gone since it would then drop every other event. Must be called with the write lock held
+func (c *Conn) keepHeartbeatFilter(ctx context.Context) {
+	if !c.heartbeatLiveness {
+		return
+	}
+	var own, others bool
+	for _, filter := range c.Filters() {
+		if filter == heartbeatFilter {
+			own = true
+		} else {
+			others = true
+		}
+	}
+
+	filter := heartbeatFilter
+	switch {
+	case others && !own:
+	case !others && own && c.heartbeatFiltered:
+		filter.Delete = true
+	default:
+		return
+	}
+	response, err := c.sendMessage(ctx, "filter", filter.BuildMessage())
+	if err == nil {
+		err = response.Err(filter)
+	}
+	if err != nil {
+		c.log().Warn("Failed to update the heartbeat filter", LogKeyError, err)
+		return
+	}
+	c.trackFilter(filter)
+	c.heartbeatFiltered = !filter.Delete
+}
+
+// closeDead - Closes the connection that was considered dead and calls onDead
+func (c *Conn) closeDead(onDead func()) {
+	c.Close()
+	if onDead != nil {
+		onDead()
+	}
+}
+
+// markReceived - Records that something was just received from FreeSWITCH
+func (c *Conn) markReceived() {
+	c.lastReceived.Store(time.Now().UnixNano())
+}
+
+func (c *Conn) lastReceivedTime() time.Time {
+	return time.Unix(0, c.lastReceived.Load())
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"log/slog"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FreeSWITCH log levels, as used by command.Log and LogRecord.Level
+const (
+	LogLevelConsole = iota
+	LogLevelAlert
+	LogLevelCrit
+	LogLevelErr
+	LogLevelWarning
+	LogLevelNotice
+	LogLevelInfo
+	LogLevelDebug
+)
+
+var logLevelNames = []string{"CONSOLE", "ALERT", "CRIT", "ERR", "WARNING", "NOTICE", "INFO", "DEBUG"}
+
+// LogListener - Called with every log/data message after logging was enabled with command.Log
+type LogListener func(record *LogRecord)
+
+// LogRecord - A FreeSWITCH log line received after enabling logging with command.Log
+type LogRecord struct {
+	Level       int    // The log level, one of the LogLevel constants
+	File        string // The source file that logged the line
+	Function    string // The function that logged the line
+	Line        int    // The line number in the source file
+	ChannelUUID string // The channel the line is about, empty for lines not about a channel
+	Text        string // The log text as FreeSWITCH would print it
+	Headers     textproto.MIMEHeader
+}
+
+// LevelName - The name of the log level as FreeSWITCH prints it e.g. DEBUG
+func (r LogRecord) LevelName() string {
+	if r.Level >= 0 && r.Level < len(logLevelNames) {
+		return logLevelNames[r.Level]
+	}
+	return strconv.Itoa(r.Level)
+}
+
+// RegisterLogListener - Registers a listener for log records. Listeners are called one at a time on the goroutine reading from FreeSWITCH,
+// so they must not block, a blocked listener holds up every reply and event of the connection. Returns the id to remove the listener with
+func (c *Conn) RegisterLogListener(listener LogListener) string {
+	c.logListenerLock.Lock()
+	defer c.logListenerLock.Unlock()
+
+	id := uuid.New().String()
+	c.logListeners[id] = listener
+	return id
+}
+
+// RemoveLogListener - Removes the log listener with the id returned from RegisterLogListener
+func (c *Conn) RemoveLogListener(id string) {
+	c.logListenerLock.Lock()
+	defer c.logListenerLock.Unlock()
+	delete(c.logListeners, id)
+}
+
+// LogRecords - Returns a channel receiving log records until the context is done or the connection is closed, the channel is then closed
+// Records are never waited for, when the buffer is full they are dropped and a warning with the number of dropped records is logged
+func (c *Conn) LogRecords(ctx context.Context, buffer int) <-chan *LogRecord {
+	records := make(chan *LogRecord, buffer)
+	var lock sync.Mutex
+	var closed bool
+	dropped := 0
+	id := c.RegisterLogListener(func(record *LogRecord) {
+		lock.Lock()
+		defer lock.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case records <- record:
+			if dropped > 0 {
+				c.log().Warn("Dropped log records, the reader is too slow", "dropped", dropped)
+				dropped = 0
+			}
+		default:
+			dropped++
+		}
+	})
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-c.runningContext.Done():
+		}
+		c.RemoveLogListener(id)
+		// The listener may still be running, it checks closed so it never sends on the closed channel
+		lock.Lock()
+		defer lock.Unlock()
+		closed = true
+		close(records)
+		if dropped > 0 {
+			c.log().Warn("Dropped log records, the reader is too slow", "dropped", dropped)
+		}
+	}()
+	return records
+}
+
+func (c *Conn) logLoop() {
+	for {
+		select {
+		case raw, ok := <-c.responseChannel(TypeLogData):
+			if !ok {
+				return
+			}
+			c.callLogListeners(parseLogRecord(raw))
+		case <-c.runningContext.Done():
+			return
+		}
+	}
+}
+
+func (c *Conn) callLogListeners(record *LogRecord) {
+	var listeners []LogListener
+	c.logListenerLock.RLock()
+	for _, listener := range c.logListeners {
+		listeners = append(listeners, listener)
+	}
+	c.logListenerLock.RUnlock()
+
+	// Called without the lock so listeners can register and remove listeners
+	for _, listener := range listeners {
+		c.safeCallLogListener(listener, record)
+	}
+}
+
+func (c *Conn) safeCallLogListener(listener LogListener, record *LogRecord) {
+	defer c.recoverPanic("log listener", slog.String(LogKeyChannelUUID, record.ChannelUUID))
+	listener(record)
+}
+
+func parseLogRecord(raw *RawResponse) *LogRecord {
+	record := &LogRecord{
+		File:     raw.GetHeader("Log-File"),
+		Function: raw.GetHeader("Log-Func"),
+		Text:     strings.TrimRight(string(raw.Body), "\r\n"),
+		Headers:  raw.Headers,
+	}
+	record.Level, _ = strconv.Atoi(raw.GetHeader("Log-Level"))
+	record.Line, _ = strconv.Atoi(raw.GetHeader("Log-Line"))
+	// Older versions of FreeSWITCH send the channel in User-Data
+	record.ChannelUUID = raw.GetHeader("Log-Unique-ID")
+	if len(record.ChannelUUID) == 0 {
+		record.ChannelUUID = raw.GetHeader("User-Data")
+	}
+	return record
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"github.com/percipia/eslgo/command"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// testLogData - Formats a log/data message
+func testLogData(level int, channelUUID, text string) string {
+	return fmt.Sprintf("Content-Type: log/data\r\nContent-Length: %d\r\nLog-Level: %d\r\nText-Channel: 3\r\nLog-File: switch_core_state_machine.c\r\n"+
+		"Log-Func: switch_core_session_run\r\nLog-Line: 710\r\nUser-Data: %s\r\n\r\n%s", len(text), level, channelUUID, text)
+}
+
+func TestConn_LogRecords(t *testing.T) {
+	conn, commands := dialTestServer(t, func(command string) string {
+		return testReply("+OK log level 7 [7]") +
+			testLogData(LogLevelDebug, "channel", "State Change CS_EXECUTE -> CS_HANGUP\n") +
+			testLogData(LogLevelWarning, "", "Something odd happened\n")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	records := conn.LogRecords(ctx, 10)
+	_, err := conn.SendCommand(ctx, command.Log{Enabled: true, Level: LogLevelDebug})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"log 7"}, commands())
+
+	record := <-records
+	assert.Equal(t, LogLevelDebug, record.Level)
+	assert.Equal(t, "DEBUG", record.LevelName())
+	assert.Equal(t, "switch_core_state_machine.c", record.File)
+	assert.Equal(t, "switch_core_session_run", record.Function)
+	assert.Equal(t, 710, record.Line)
+	assert.Equal(t, "channel", record.ChannelUUID)
+	assert.Equal(t, "State Change CS_EXECUTE -> CS_HANGUP", record.Text)
+
+	record = <-records
+	assert.Equal(t, "WARNING", record.LevelName())
+	assert.Empty(t, record.ChannelUUID)
+
+	cancel()
+	for range records {
+	}
+}
+
+func TestConn_LogRecords_SlowReader(t *testing.T) {
+	conn, _ := dialTestServer(t, func(command string) string {
+		return testReply("+OK log level 7 [7]") + testLogData(LogLevelInfo, "", "first\n") + testLogData(LogLevelInfo, "", "second\n")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Nobody reads the records, the second one is dropped instead of holding up the reply to the next command
+	records := conn.LogRecords(ctx, 1)
+	_, err := conn.SendCommand(ctx, command.Log{Enabled: true, Level: LogLevelInfo})
+	assert.Nil(t, err)
+	_, err = conn.SendCommand(ctx, command.Log{Enabled: true, Level: LogLevelInfo})
+	assert.Nil(t, err)
+	assert.Equal(t, "first", (<-records).Text)
+
+	cancel()
+	for range records {
+	}
+}
+
+func TestConn_RemoveLogListener_FromListener(t *testing.T) {
+	conn, _ := dialTestServer(t, func(command string) string {
+		return testReply("+OK log level 7 [7]") + testLogData(LogLevelInfo, "", "first\n")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	removed := make(chan struct{})
+	var id string
+	id = conn.RegisterLogListener(func(record *LogRecord) {
+		conn.RemoveLogListener(id)
+		close(removed)
+	})
+	_, err := conn.SendCommand(ctx, command.Log{Enabled: true, Level: LogLevelInfo})
+	assert.Nil(t, err)
+	select {
+	case <-removed:
+	case <-ctx.Done():
+		t.Fatal("listener removing itself deadlocked")
+	}
+}
+
+func TestParseLogRecord_LogUniqueID(t *testing.T) {
+	record := parseLogRecord(&RawResponse{
+		Headers: map[string][]string{"Log-Level": {"3"}, "Log-Unique-Id": {"channel"}, "User-Data": {"other"}},
+		Body:    []byte("failed\n"),
+	})
+	assert.Equal(t, "channel", record.ChannelUUID)
+	assert.Equal(t, "ERR", record.LevelName())
+}
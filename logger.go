@@ -1,7 +1,11 @@
 package eslgo
 
 import (
+	"context"
 	"log"
+	"log/slog"
+	"strconv"
+	"strings"
 )
 
 type Logger interface {
@@ -11,27 +15,122 @@ type Logger interface {
 	Error(format string, args ...interface{})
 }
 
+// Attribute keys attached to the structured logs emitted by eslgo
+const (
+	LogKeyRemoteAddr  = "remote_addr"
+	LogKeyDirection   = "direction"
+	LogKeyChannelUUID = "channel_uuid"
+	LogKeyCommand     = "command"
+	LogKeyEvent       = "event"
+	LogKeyError       = "error"
+)
+
 type NilLogger struct{}
 type NormalLogger struct{}
 
 func (l NormalLogger) Debug(format string, args ...interface{}) {
-	log.Print("DEBUG: ")
-	log.Printf(format, args...)
+	log.Printf("DEBUG: "+format, args...)
 }
 func (l NormalLogger) Info(format string, args ...interface{}) {
-	log.Print("INFO: ")
-	log.Printf(format, args...)
+	log.Printf("INFO: "+format, args...)
 }
 func (l NormalLogger) Warn(format string, args ...interface{}) {
-	log.Print("WARN: ")
-	log.Printf(format, args...)
+	log.Printf("WARN: "+format, args...)
 }
 func (l NormalLogger) Error(format string, args ...interface{}) {
-	log.Print("ERROR: ")
-	log.Printf(format, args...)
+	log.Printf("ERROR: "+format, args...)
 }
 
 func (l NilLogger) Debug(string, ...interface{}) {}
 func (l NilLogger) Info(string, ...interface{})  {}
 func (l NilLogger) Warn(string, ...interface{})  {}
 func (l NilLogger) Error(string, ...interface{}) {}
+
+// LoggerHandler - An slog.Handler that forwards structured records to a printf-style Logger.
+// Attributes are appended to the message as key=value pairs so existing Logger implementations keep working.
+type LoggerHandler struct {
+	logger Logger
+	attrs  string
+	group  string
+}
+
+// NewLoggerHandler - Creates an slog.Handler that writes to the provided Logger
+func NewLoggerHandler(logger Logger) *LoggerHandler {
+	if logger == nil {
+		logger = NilLogger{}
+	}
+	return &LoggerHandler{logger: logger}
+}
+
+// Enabled - Implements slog.Handler, records are discarded without formatting when using NilLogger
+func (h *LoggerHandler) Enabled(context.Context, slog.Level) bool {
+	_, isNil := h.logger.(NilLogger)
+	return !isNil
+}
+
+// Handle - Implements slog.Handler, calls the Logger function matching the record level
+func (h *LoggerHandler) Handle(_ context.Context, record slog.Record) error {
+	var builder strings.Builder
+	builder.WriteString(record.Message)
+	builder.WriteString(h.attrs)
+	record.Attrs(func(attr slog.Attr) bool {
+		writeAttr(&builder, h.group, attr)
+		return true
+	})
+
+	switch {
+	case record.Level < slog.LevelInfo:
+		h.logger.Debug("%s\n", builder.String())
+	case record.Level < slog.LevelWarn:
+		h.logger.Info("%s\n", builder.String())
+	case record.Level < slog.LevelError:
+		h.logger.Warn("%s\n", builder.String())
+	default:
+		h.logger.Error("%s\n", builder.String())
+	}
+	return nil
+}
+
+// WithAttrs - Implements slog.Handler
+func (h *LoggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var builder strings.Builder
+	builder.WriteString(h.attrs)
+	for _, attr := range attrs {
+		writeAttr(&builder, h.group, attr)
+	}
+	return &LoggerHandler{logger: h.logger, attrs: builder.String(), group: h.group}
+}
+
+// WithGroup - Implements slog.Handler, groups are represented by prefixing the keys with the group name
+func (h *LoggerHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &LoggerHandler{logger: h.logger, attrs: h.attrs, group: h.group + name + "."}
+}
+
+func writeAttr(builder *strings.Builder, group string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			group += attr.Key + "."
+		}
+		for _, groupAttr := range attr.Value.Group() {
+			writeAttr(builder, group, groupAttr)
+		}
+		return
+	}
+
+	value := attr.Value.String()
+	if strings.ContainsAny(value, " \t\r\n\"=") {
+		value = strconv.Quote(value)
+	}
+	builder.WriteString(" ")
+	builder.WriteString(group)
+	builder.WriteString(attr.Key)
+	builder.WriteString("=")
+	builder.WriteString(value)
+}
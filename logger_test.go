@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"log/slog"
+	"testing"
+)
+
+type recordingLogger struct {
+	lines []string
+}
+
+func (r *recordingLogger) record(level, format string, args ...interface{}) {
+	r.lines = append(r.lines, level+": "+fmt.Sprintf(format, args...))
+}
+
+func (r *recordingLogger) Debug(format string, args ...interface{}) {
+	r.record("DEBUG", format, args...)
+}
+func (r *recordingLogger) Info(format string, args ...interface{}) { r.record("INFO", format, args...) }
+func (r *recordingLogger) Warn(format string, args ...interface{}) { r.record("WARN", format, args...) }
+func (r *recordingLogger) Error(format string, args ...interface{}) {
+	r.record("ERROR", format, args...)
+}
+
+func TestLoggerHandler_Handle(t *testing.T) {
+	recorder := &recordingLogger{}
+	logger := slog.New(NewLoggerHandler(recorder)).With(LogKeyDirection, "inbound")
+
+	logger.Debug("debug message")
+	logger.Info("info message", LogKeyCommand, "api")
+	logger.WithGroup("conn").Warn("warn message", LogKeyEvent, "CHANNEL_ANSWER")
+	logger.Error("error message", LogKeyError, "connection reset by peer")
+
+	assert.Equal(t, []string{
+		"DEBUG: debug message direction=inbound\n",
+		"INFO: info message direction=inbound command=api\n",
+		"WARN: warn message direction=inbound conn.event=CHANNEL_ANSWER\n",
+		"ERROR: error message direction=inbound error=\"connection reset by peer\"\n",
+	}, recorder.lines)
+}
+
+func TestLoggerHandler_Enabled(t *testing.T) {
+	assert.False(t, NewLoggerHandler(nil).Enabled(context.Background(), slog.LevelError))
+	assert.True(t, NewLoggerHandler(NormalLogger{}).Enabled(context.Background(), slog.LevelDebug))
+}
@@ -0,0 +1,330 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/percipia/eslgo"
+	"github.com/percipia/eslgo/command"
+	"github.com/percipia/eslgo/command/call"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Codec - Describes the audio FreeSWITCH sends over the stream
+type Codec struct {
+	Name          string        // L16 unless the stream is native, then the channel read codec e.g. PCMU
+	SampleRate    int           // Samples per second
+	Channels      int           // Number of interleaved channels
+	FrameDuration time.Duration // How much audio FreeSWITCH sends in each frame, the packetization time
+}
+
+// FrameSize - The size of a single frame in bytes
+func (c Codec) FrameSize() int {
+	samples := c.SampleRate * int(c.FrameDuration/time.Millisecond) / 1000 * c.Channels
+	switch strings.ToUpper(c.Name) {
+	case "PCMU", "PCMA":
+		return samples
+	}
+	// L16, 16 bit signed linear
+	return samples * 2
+}
+
+// Options - Used to start a media stream for a channel
+type Options struct {
+	Network           string        // udp or tcp, defaults to udp
+	ListenAddress     string        // The local address our socket listens on. Defaults to 127.0.0.1:0
+	AdvertiseAddress  string        // The address FreeSWITCH sends to, needed when ListenAddress is not reachable by FreeSWITCH e.g. 0.0.0.0. Defaults to the listen address
+	FreeSWITCHAddress string        // The address FreeSWITCH binds its side of the stream to, the local-ip and local-port of unicast. Required
+	Native            bool          // Receive frames in the channel codec instead of L16
+	Codec             Codec         // Overrides codec detection, fields left empty are detected from the channel
+	Paced             bool          // Write blocks so frames are sent in real time, useful when streaming prerecorded audio
+	AcceptTimeout     time.Duration // How long to wait for FreeSWITCH to connect when using tcp. Defaults to 5 seconds
+}
+
+// Stream - The Go side of a unicast media stream. Reads return audio from the channel and writes send audio to the channel
+// UDP reads return a single frame, use a buffer of at least Codec.FrameSize bytes
+type Stream struct {
+	UUID  string // The channel being streamed
+	Codec Codec  // The audio format of the stream
+
+	conn      net.Conn
+	packet    net.PacketConn
+	remote    net.Addr
+	writeLock sync.Mutex
+	nextFrame time.Time
+	paced     bool
+}
+
+// Start - Opens the local socket and issues unicast for the channel. The channel must be parked for FreeSWITCH to stream media
+func Start(ctx context.Context, conn *eslgo.Conn, uuid string, opts Options) (*Stream, error) {
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	codec, err := detectCodec(ctx, conn, uuid, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &Stream{
+		UUID:  uuid,
+		Codec: codec,
+		paced: opts.Paced,
+	}
+	var listener net.Listener
+	var local net.Addr
+	if opts.Network == "tcp" {
+		listener, err = net.Listen("tcp", opts.ListenAddress)
+		if err != nil {
+			return nil, err
+		}
+		defer listener.Close()
+		local = listener.Addr()
+	} else {
+		stream.packet, err = net.ListenPacket("udp", opts.ListenAddress)
+		if err != nil {
+			return nil, err
+		}
+		local = stream.packet.LocalAddr()
+	}
+
+	advertise, freeswitch, err := opts.addresses(local)
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+	stream.remote = freeswitch
+
+	// From the FreeSWITCH point of view its address is local and ours is remote
+	_, err = conn.SendCommandChecked(ctx, call.Unicast{
+		UUID:   uuid,
+		Local:  freeswitch,
+		Remote: advertise,
+		Flags:  opts.flags(),
+	})
+	if err != nil {
+		stream.Close()
+		return nil, err
+	}
+
+	if listener != nil {
+		deadline := time.Now().Add(opts.AcceptTimeout)
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		_ = listener.(*net.TCPListener).SetDeadline(deadline)
+		stream.conn, err = listener.Accept()
+		if err != nil {
+			_ = listener.Close()
+			stream.Close()
+			stopUnicast(ctx, conn, uuid)
+			return nil, fmt.Errorf("FreeSWITCH did not connect to the media stream: %w", err)
+		}
+	}
+	return stream, nil
+}
+
+// stopUnicast - Breaks the channel out of park, which ends the unicast FreeSWITCH may still set up. The context may already be done so a new timeout is used
+func stopUnicast(ctx context.Context, conn *eslgo.Conn, uuid string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	_, _ = conn.SendCommandChecked(ctx, command.API{
+		Command:   "uuid_break",
+		Arguments: uuid,
+	})
+}
+
+// Read - Reads audio from the channel
+func (s *Stream) Read(p []byte) (int, error) {
+	if s.conn != nil {
+		return s.conn.Read(p)
+	}
+	for {
+		n, from, err := s.packet.ReadFrom(p)
+		if err != nil {
+			return n, err
+		}
+		// Ignore anything that is not FreeSWITCH
+		if sameHost(from, s.remote) {
+			return n, nil
+		}
+	}
+}
+
+// Write - Writes audio to the channel. With udp the audio is split into frames of Codec.FrameSize bytes, each sent in its own packet
+func (s *Stream) Write(p []byte) (int, error) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	frameSize := s.Codec.FrameSize()
+	if frameSize <= 0 {
+		frameSize = len(p)
+	}
+	written := 0
+	for written < len(p) {
+		end := written + frameSize
+		if end > len(p) {
+			end = len(p)
+		}
+		s.pace()
+		var err error
+		if s.conn != nil {
+			_, err = s.conn.Write(p[written:end])
+		} else {
+			_, err = s.packet.WriteTo(p[written:end], s.remote)
+		}
+		if err != nil {
+			return written, err
+		}
+		written = end
+	}
+	return written, nil
+}
+
+// Close - Closes the local socket, FreeSWITCH stops streaming when the channel leaves park or hangs up
+func (s *Stream) Close() error {
+	if s.conn != nil {
+		return s.conn.Close()
+	}
+	if s.packet != nil {
+		return s.packet.Close()
+	}
+	return nil
+}
+
+// LocalAddr - The address of our side of the stream
+func (s *Stream) LocalAddr() net.Addr {
+	if s.conn != nil {
+		return s.conn.LocalAddr()
+	}
+	return s.packet.LocalAddr()
+}
+
+// pace - Sleeps until the next frame should be sent when pacing is enabled
+func (s *Stream) pace() {
+	if !s.paced || s.Codec.FrameDuration <= 0 {
+		return
+	}
+	now := time.Now()
+	if s.nextFrame.Before(now) {
+		s.nextFrame = now
+	}
+	time.Sleep(s.nextFrame.Sub(now))
+	s.nextFrame = s.nextFrame.Add(s.Codec.FrameDuration)
+}
+
+func (opts Options) withDefaults() (Options, error) {
+	if len(opts.Network) == 0 {
+		opts.Network = "udp"
+	}
+	if opts.Network != "udp" && opts.Network != "tcp" {
+		return opts, fmt.Errorf("unsupported media network %q", opts.Network)
+	}
+	if len(opts.ListenAddress) == 0 {
+		opts.ListenAddress = "127.0.0.1:0"
+	}
+	if len(opts.FreeSWITCHAddress) == 0 {
+		return opts, errors.New("FreeSWITCHAddress is required")
+	}
+	if opts.AcceptTimeout <= 0 {
+		opts.AcceptTimeout = 5 * time.Second
+	}
+	return opts, nil
+}
+
+// addresses - Resolves the address we advertise to FreeSWITCH and the address FreeSWITCH binds
+func (opts Options) addresses(local net.Addr) (net.Addr, net.Addr, error) {
+	advertise := local.String()
+	if len(opts.AdvertiseAddress) > 0 {
+		advertise = opts.AdvertiseAddress
+		// Allow only the host to be specified, keeping the port we are listening on
+		if _, _, err := net.SplitHostPort(advertise); err != nil {
+			_, port, _ := net.SplitHostPort(local.String())
+			advertise = net.JoinHostPort(advertise, port)
+		}
+	}
+
+	resolve := func(address string) (net.Addr, error) {
+		if opts.Network == "tcp" {
+			return net.ResolveTCPAddr("tcp", address)
+		}
+		return net.ResolveUDPAddr("udp", address)
+	}
+	advertiseAddr, err := resolve(advertise)
+	if err != nil {
+		return nil, nil, err
+	}
+	host, _, _ := net.SplitHostPort(advertiseAddr.String())
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		return nil, nil, fmt.Errorf("FreeSWITCH cannot send media to %s, set AdvertiseAddress", advertise)
+	}
+	freeswitchAddr, err := resolve(opts.FreeSWITCHAddress)
+	return advertiseAddr, freeswitchAddr, err
+}
+
+func (opts Options) flags() string {
+	if opts.Native {
+		return "native"
+	}
+	return ""
+}
+
+// detectCodec - Fills in the codec fields not set in the options from the channel variables
+func detectCodec(ctx context.Context, conn *eslgo.Conn, uuid string, opts Options) (Codec, error) {
+	codec := opts.Codec
+	if codec.SampleRate == 0 {
+		rate, err := channelVariable(ctx, conn, uuid, "read_rate")
+		if err != nil {
+			return codec, err
+		}
+		codec.SampleRate, err = strconv.Atoi(rate)
+		if err != nil {
+			return codec, fmt.Errorf("unable to detect the sample rate of the channel: %w", err)
+		}
+	}
+	if len(codec.Name) == 0 {
+		codec.Name = "L16"
+		if opts.Native {
+			name, err := channelVariable(ctx, conn, uuid, "read_codec")
+			if err != nil {
+				return codec, err
+			}
+			codec.Name = name
+		}
+	}
+	if codec.Channels == 0 {
+		codec.Channels = 1
+	}
+	if codec.FrameDuration == 0 {
+		codec.FrameDuration = 20 * time.Millisecond
+	}
+	return codec, nil
+}
+
+func channelVariable(ctx context.Context, conn *eslgo.Conn, uuid, name string) (string, error) {
+	value, err := conn.GetVar(ctx, uuid, name)
+	if err != nil {
+		return "", fmt.Errorf("unable to get channel variable %s: %w", name, err)
+	}
+	return value.String(), nil
+}
+
+func sameHost(a, b net.Addr) bool {
+	hostA, _, _ := net.SplitHostPort(a.String())
+	hostB, _, _ := net.SplitHostPort(b.String())
+	return net.ParseIP(hostA).Equal(net.ParseIP(hostB))
+}
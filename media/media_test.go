@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package media
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/percipia/eslgo"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFreeSWITCH - A minimal ESL server answering auth and api commands, unicast headers are sent on the returned channel and other api commands on the other
+func fakeFreeSWITCH(t *testing.T) (string, <-chan textproto.MIMEHeader, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	unicast := make(chan textproto.MIMEHeader, 1)
+	apis := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := textproto.NewReader(bufio.NewReader(conn))
+		fmt.Fprint(conn, "Content-Type: auth/request\r\n\r\n")
+		for {
+			line, err := reader.ReadLine()
+			if err != nil {
+				return
+			}
+			headers, err := reader.ReadMIMEHeader()
+			if err != nil {
+				return
+			}
+			reply := "+OK"
+			switch {
+			case strings.HasPrefix(line, "api uuid_getvar"):
+				body := "8000"
+				if strings.HasSuffix(line, "read_codec") {
+					body = "PCMU"
+				}
+				fmt.Fprintf(conn, "Content-Type: api/response\r\nContent-Length: %d\r\n\r\n%s", len(body), body)
+				continue
+			case strings.HasPrefix(line, "api "):
+				apis <- line
+				fmt.Fprint(conn, "Content-Type: api/response\r\nContent-Length: 3\r\n\r\n+OK")
+				continue
+			case strings.HasPrefix(line, "sendmsg"):
+				unicast <- headers
+			}
+			fmt.Fprintf(conn, "Content-Type: command/reply\r\nReply-Text: %s\r\n\r\n", reply)
+		}
+	}()
+	return listener.Addr().String(), unicast, apis
+}
+
+func dial(t *testing.T, address string) *eslgo.Conn {
+	opts := eslgo.DefaultInboundOptions
+	opts.Logger = eslgo.NilLogger{}
+	opts.ExitTimeout = 100 * time.Millisecond
+	conn, err := opts.Dial(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(conn.Close)
+	return conn
+}
+
+func TestStart_UDP(t *testing.T) {
+	address, unicast, _ := fakeFreeSWITCH(t)
+	conn := dial(t, address)
+
+	// Stands in for the FreeSWITCH side of the media stream
+	freeswitch, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer freeswitch.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := Start(ctx, conn, "channel", Options{
+		FreeSWITCHAddress: freeswitch.LocalAddr().String(),
+		Native:            true,
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer stream.Close()
+	assert.Equal(t, Codec{Name: "PCMU", SampleRate: 8000, Channels: 1, FrameDuration: 20 * time.Millisecond}, stream.Codec)
+	assert.Equal(t, 160, stream.Codec.FrameSize())
+
+	headers := <-unicast
+	_, freeswitchPort, _ := net.SplitHostPort(freeswitch.LocalAddr().String())
+	_, streamPort, _ := net.SplitHostPort(stream.LocalAddr().String())
+	assert.Equal(t, "unicast", headers.Get("Call-Command"))
+	assert.Equal(t, freeswitchPort, headers.Get("Local-Port"))
+	assert.Equal(t, streamPort, headers.Get("Remote-Port"))
+	assert.Equal(t, "udp", headers.Get("Transport"))
+	assert.Equal(t, "native", headers.Get("Flags"))
+
+	// Audio from the channel
+	_, err = freeswitch.WriteTo([]byte("frame"), stream.LocalAddr())
+	assert.Nil(t, err)
+	buffer := make([]byte, stream.Codec.FrameSize())
+	n, err := stream.Read(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, "frame", string(buffer[:n]))
+
+	// Audio to the channel is split into frames
+	n, err = stream.Write(make([]byte, 400))
+	assert.Nil(t, err)
+	assert.Equal(t, 400, n)
+	for _, expected := range []int{160, 160, 80} {
+		n, _, err = freeswitch.ReadFrom(buffer)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, n)
+	}
+}
+
+func TestStart_TCP(t *testing.T) {
+	address, unicast, _ := fakeFreeSWITCH(t)
+	conn := dial(t, address)
+
+	go func() {
+		headers := <-unicast
+		media, err := net.Dial("tcp", net.JoinHostPort(headers.Get("Remote-Ip"), headers.Get("Remote-Port")))
+		if err != nil {
+			return
+		}
+		defer media.Close()
+		_, _ = media.Write([]byte("audio"))
+		time.Sleep(100 * time.Millisecond)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stream, err := Start(ctx, conn, "channel", Options{
+		Network:           "tcp",
+		FreeSWITCHAddress: "127.0.0.1:9000",
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer stream.Close()
+	assert.Equal(t, "L16", stream.Codec.Name)
+	assert.Equal(t, 320, stream.Codec.FrameSize())
+
+	buffer := make([]byte, 16)
+	n, err := stream.Read(buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, "audio", string(buffer[:n]))
+}
+
+func TestStart_TCPAcceptTimeout(t *testing.T) {
+	address, unicast, apis := fakeFreeSWITCH(t)
+	conn := dial(t, address)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// Nothing connects to the stream
+	_, err := Start(ctx, conn, "channel", Options{
+		Network:           "tcp",
+		FreeSWITCHAddress: "127.0.0.1:9000",
+		AcceptTimeout:     50 * time.Millisecond,
+	})
+	assert.Error(t, err)
+
+	headers := <-unicast
+	// The listener is closed and the unicast is stopped on the FreeSWITCH side
+	_, err = net.Dial("tcp", net.JoinHostPort(headers.Get("Remote-Ip"), headers.Get("Remote-Port")))
+	assert.Error(t, err)
+	select {
+	case line := <-apis:
+		assert.Equal(t, "api uuid_break channel", line)
+	case <-ctx.Done():
+		t.Fatal("unicast was not stopped")
+	}
+}
+
+func TestOptions_Validation(t *testing.T) {
+	_, err := Options{}.withDefaults()
+	assert.NotNil(t, err)
+	_, err = Options{Network: "sctp", FreeSWITCHAddress: "127.0.0.1:9000"}.withDefaults()
+	assert.NotNil(t, err)
+
+	opts, _ := Options{ListenAddress: "0.0.0.0:0", FreeSWITCHAddress: "127.0.0.1:9000"}.withDefaults()
+	_, _, err = opts.addresses(&net.UDPAddr{IP: net.IPv4zero, Port: 5000})
+	assert.NotNil(t, err)
+	opts.AdvertiseAddress = "10.0.0.5"
+	advertise, _, err := opts.addresses(&net.UDPAddr{IP: net.IPv4zero, Port: 5000})
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.5:5000", advertise.String())
+}
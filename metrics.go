@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import "time"
+
+// Metrics - An optional hook for observing what eslgo is doing at runtime. Implementations must be safe for concurrent use.
+// See the metrics/prometheus package for a ready-made Prometheus collector.
+type Metrics interface {
+	// CommandSent - Called when a command has been written to FreeSWITCH. name is the ESL command, e.g. "api" or "sendmsg"
+	CommandSent(name string)
+	// CommandReplied - Called when FreeSWITCH replies to a command. isError is true for -ERR replies
+	CommandReplied(name string, latency time.Duration, isError bool)
+	// EventReceived - Called for every event received, name is the Event-Name header
+	EventReceived(name string)
+	// ListenerQueueChanged - Called with +1 when an event listener starts running and -1 when it finishes
+	ListenerQueueChanged(delta int)
+	// ResponseDropped - Called when a message was dropped because no one handled it in time
+	ResponseDropped(contentType string)
+	// Reconnected - Called when a connection to address has been re-established after being lost
+	Reconnected(address string)
+	// ConnectionOpened - Called when a new connection is established, direction is either "inbound" or "outbound"
+	ConnectionOpened(direction string)
+	// ConnectionClosed - Called when a connection established with ConnectionOpened is closed
+	ConnectionClosed(direction string)
+}
+
+// NilMetrics - A Metrics implementation that discards everything, used when Options.Metrics is nil
+type NilMetrics struct{}
+
+func (NilMetrics) CommandSent(string)                         {}
+func (NilMetrics) CommandReplied(string, time.Duration, bool) {}
+func (NilMetrics) EventReceived(string)                       {}
+func (NilMetrics) ListenerQueueChanged(int)                   {}
+func (NilMetrics) ResponseDropped(string)                     {}
+func (NilMetrics) Reconnected(string)                         {}
+func (NilMetrics) ConnectionOpened(string)                    {}
+func (NilMetrics) ConnectionClosed(string)                    {}
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package prometheus
+
+import (
+	"github.com/percipia/eslgo"
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Collector - Implements both eslgo.Metrics and prometheus.Collector.
+// Set it as Options.Metrics on your connections and register it with a prometheus.Registerer.
+type Collector struct {
+	commandsSent      *stdprometheus.CounterVec
+	commandLatency    *stdprometheus.HistogramVec
+	commandErrors     *stdprometheus.CounterVec
+	eventsReceived    *stdprometheus.CounterVec
+	listenerQueue     stdprometheus.Gauge
+	responsesDropped  *stdprometheus.CounterVec
+	reconnects        *stdprometheus.CounterVec
+	activeConnections *stdprometheus.GaugeVec
+}
+
+var _ eslgo.Metrics = (*Collector)(nil)
+var _ stdprometheus.Collector = (*Collector)(nil)
+
+// NewCollector - Creates a new Collector with all metric names prefixed with the provided namespace, e.g. "myapp" results in "myapp_eslgo_commands_sent_total"
+func NewCollector(namespace string) *Collector {
+	const subsystem = "eslgo"
+	return &Collector{
+		commandsSent: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "commands_sent_total",
+			Help:      "Number of ESL commands sent to FreeSWITCH by command type.",
+		}, []string{"command"}),
+		commandLatency: stdprometheus.NewHistogramVec(stdprometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "command_reply_seconds",
+			Help:      "Time between sending an ESL command and receiving the reply from FreeSWITCH.",
+			Buckets:   stdprometheus.ExponentialBuckets(0.001, 2, 14),
+		}, []string{"command"}),
+		commandErrors: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "command_errors_total",
+			Help:      "Number of -ERR replies received from FreeSWITCH by command type.",
+		}, []string{"command"}),
+		eventsReceived: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "events_received_total",
+			Help:      "Number of events received from FreeSWITCH by Event-Name.",
+		}, []string{"event"}),
+		listenerQueue: stdprometheus.NewGauge(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "event_listeners_running",
+			Help:      "Number of event listener calls currently running.",
+		}),
+		responsesDropped: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "responses_dropped_total",
+			Help:      "Number of messages from FreeSWITCH dropped because no one handled them in time.",
+		}, []string{"content_type"}),
+		reconnects: stdprometheus.NewCounterVec(stdprometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "reconnects_total",
+			Help:      "Number of times a connection to FreeSWITCH was re-established.",
+		}, []string{"address"}),
+		activeConnections: stdprometheus.NewGaugeVec(stdprometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: subsystem,
+			Name:      "connections_active",
+			Help:      "Number of open ESL connections by direction.",
+		}, []string{"direction"}),
+	}
+}
+
+func (c *Collector) collectors() []stdprometheus.Collector {
+	return []stdprometheus.Collector{
+		c.commandsSent,
+		c.commandLatency,
+		c.commandErrors,
+		c.eventsReceived,
+		c.listenerQueue,
+		c.responsesDropped,
+		c.reconnects,
+		c.activeConnections,
+	}
+}
+
+// Describe - Implements prometheus.Collector
+func (c *Collector) Describe(descs chan<- *stdprometheus.Desc) {
+	for _, collector := range c.collectors() {
+		collector.Describe(descs)
+	}
+}
+
+// Collect - Implements prometheus.Collector
+func (c *Collector) Collect(metrics chan<- stdprometheus.Metric) {
+	for _, collector := range c.collectors() {
+		collector.Collect(metrics)
+	}
+}
+
+func (c *Collector) CommandSent(name string) {
+	c.commandsSent.WithLabelValues(name).Inc()
+}
+
+func (c *Collector) CommandReplied(name string, latency time.Duration, isError bool) {
+	c.commandLatency.WithLabelValues(name).Observe(latency.Seconds())
+	if isError {
+		c.commandErrors.WithLabelValues(name).Inc()
+	}
+}
+
+func (c *Collector) EventReceived(name string) {
+	c.eventsReceived.WithLabelValues(name).Inc()
+}
+
+func (c *Collector) ListenerQueueChanged(delta int) {
+	c.listenerQueue.Add(float64(delta))
+}
+
+func (c *Collector) ResponseDropped(contentType string) {
+	c.responsesDropped.WithLabelValues(contentType).Inc()
+}
+
+func (c *Collector) Reconnected(address string) {
+	c.reconnects.WithLabelValues(address).Inc()
+}
+
+func (c *Collector) ConnectionOpened(direction string) {
+	c.activeConnections.WithLabelValues(direction).Inc()
+}
+
+func (c *Collector) ConnectionClosed(direction string) {
+	c.activeConnections.WithLabelValues(direction).Dec()
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package prometheus
+
+import (
+	stdprometheus "github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCollector(t *testing.T) {
+	collector := NewCollector("test")
+	registry := stdprometheus.NewRegistry()
+	assert.Nil(t, registry.Register(collector))
+
+	collector.CommandSent("api")
+	collector.CommandSent("api")
+	collector.CommandReplied("api", 5*time.Millisecond, false)
+	collector.CommandReplied("api", 10*time.Millisecond, true)
+	collector.EventReceived("CHANNEL_ANSWER")
+	collector.ListenerQueueChanged(1)
+	collector.ListenerQueueChanged(1)
+	collector.ListenerQueueChanged(-1)
+	collector.ResponseDropped("text/event-plain")
+	collector.ConnectionOpened("outbound")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(collector.commandsSent.WithLabelValues("api")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.commandErrors.WithLabelValues("api")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.eventsReceived.WithLabelValues("CHANNEL_ANSWER")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.listenerQueue))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.responsesDropped.WithLabelValues("text/event-plain")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(collector.activeConnections.WithLabelValues("outbound")))
+	assert.Equal(t, 1, testutil.CollectAndCount(collector, "test_eslgo_command_reply_seconds"))
+}
@@ -14,6 +14,7 @@ import (
 	"context"
 	"errors"
 	"github.com/percipia/eslgo/command"
+	"log/slog"
 	"net"
 	"time"
 )
@@ -51,9 +52,8 @@ func (opts OutboundOptions) ListenAndServe(address string, handler OutboundHandl
 	if err != nil {
 		return err
 	}
-	if opts.Logger != nil {
-		opts.Logger.Info("Listening for new ESL connections on %s\n", listener.Addr().String())
-	}
+	logger := opts.newLogger()
+	logger.Info("Listening for new ESL connections", "address", listener.Addr().String())
 	for {
 		c, err := listener.Accept()
 		if err != nil {
@@ -61,15 +61,13 @@ func (opts OutboundOptions) ListenAndServe(address string, handler OutboundHandl
 		}
 		conn := newConnection(c, true, opts.Options)
 
-		conn.logger.Info("New outbound connection from %s\n", c.RemoteAddr().String())
+		conn.log().Info("New outbound connection")
 		go conn.dummyLoop()
 		// Does not call the handler directly to ensure closing cleanly
 		go conn.outboundHandle(handler, opts.ConnectionDelay, opts.ConnectTimeout)
 	}
 
-	if opts.Logger != nil {
-		opts.Logger.Info("Outbound server shutting down")
-	}
+	logger.Info("Outbound server shutting down")
 	return errors.New("connection closed")
 }
 
@@ -78,12 +76,13 @@ func (c *Conn) outboundHandle(handler OutboundHandler, connectionDelay, connectT
 	response, err := c.SendCommand(ctx, command.Connect{})
 	cancel()
 	if err != nil {
-		c.logger.Warn("Error connecting to %s error %s", c.conn.RemoteAddr().String(), err.Error())
+		c.log().Warn("Error connecting", LogKeyCommand, "connect", LogKeyError, err)
 		// Try closing cleanly first
 		c.Close() // Not ExitAndClose since this error connection is most likely from communication failure
 		return
 	}
-	handler(c.runningContext, c, response)
+	c.withLogAttrs(slog.String(LogKeyChannelUUID, response.ChannelUUID()))
+	c.callOutboundHandler(handler, response)
 	// XXX This is ugly, the issue with short lived async sockets on our end is if they complete too fast we can actually
 	// close the connection before FreeSWITCH is in a state to close the connection on their end. 25ms is an magic value
 	// found by testing to have no failures on my test system. I started at 1 second and reduced as far as I could go.
@@ -92,17 +91,39 @@ func (c *Conn) outboundHandle(handler OutboundHandler, connectionDelay, connectT
 	c.ExitAndClose()
 }
 
+// callOutboundHandler - Calls the user handler recovering any panic so the cleanup in outboundHandle still runs
+func (c *Conn) callOutboundHandler(handler OutboundHandler, connectResponse *RawResponse) {
+	channelUUID := connectResponse.ChannelUUID()
+	ctx, span := c.tracer.Start(c.runningContext, "ESL outbound call", SpanKindServer)
+	defer span.End()
+	span.SetAttribute(TraceKeyDirection, c.direction())
+	span.SetAttribute(TraceKeyChannelUUID, channelUUID)
+	span.SetAttribute(TraceKeyDestination, connectResponse.GetHeader("Caller-Destination-Number"))
+	if _, ok := c.tracer.(NilTracer); !ok {
+		listenerID := c.TraceChannelEvents(span, channelUUID)
+		defer c.RemoveEventListener(channelUUID, listenerID)
+	}
+
+	defer c.recoverPanic("outbound handler")
+	handler(ctx, c, connectResponse)
+}
+
 func (c *Conn) dummyLoop() {
 	select {
-	case <-c.responseChannels[TypeDisconnect]:
-		c.logger.Info("Disconnect outbound connection", c.conn.RemoteAddr())
+	case _, ok := <-c.responseChannel(TypeDisconnect):
+		if !ok {
+			return
+		}
+		c.log().Info("Disconnect outbound connection")
 		if c.closeDelay >= 0 {
-			time.AfterFunc(c.closeDelay*time.Second, func() {
+			time.AfterFunc(c.closeDelay, func() {
 				c.Close()
 			})
 		}
-	case <-c.responseChannels[TypeAuthRequest]:
-		c.logger.Debug("Ignoring auth request on outbound connection", c.conn.RemoteAddr())
+	case _, ok := <-c.responseChannel(TypeAuthRequest):
+		if ok {
+			c.log().Debug("Ignoring auth request on outbound connection")
+		}
 	case <-c.runningContext.Done():
 		return
 	}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"github.com/percipia/eslgo/command"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestConn_outboundHandlePanic(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.Logger = NilLogger{}
+	opts.ExitTimeout = 100 * time.Millisecond
+	tracer := &recordingTracer{}
+	opts.Tracer = tracer
+
+	panicked := make(chan interface{}, 1)
+	opts.OnPanic = func(recovered interface{}, stack []byte) {
+		panicked <- recovered
+	}
+	connection := newConnection(client, true, opts)
+	defer server.Close()
+
+	done := make(chan struct{})
+	go func() {
+		connection.outboundHandle(func(ctx context.Context, conn *Conn, connectResponse *RawResponse) {
+			panic("handler exploded")
+		}, 0, 5*time.Second)
+		close(done)
+	}()
+
+	serverReader := bufio.NewReader(server)
+	incomingCommand, err := serverReader.ReadString('\r')
+	assert.Nil(t, err)
+	assert.Equal(t, "connect\r", incomingCommand)
+	_, err = server.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK\r\n\r\n"))
+	assert.Nil(t, err)
+
+	// Drain whatever else is written, the exit command should be sent as part of the cleanup
+	go func() {
+		_, _ = serverReader.Discard(1 << 20)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("outbound handler cleanup did not complete")
+	}
+	assert.Equal(t, "handler exploded", <-panicked)
+	assert.Error(t, connection.runningContext.Err())
+	kinds := make(map[string]SpanKind)
+	for _, span := range tracer.spans {
+		kinds[span.name] = span.kind
+	}
+	assert.Equal(t, SpanKindClient, kinds["ESL connect"])
+	assert.Equal(t, SpanKindServer, kinds["ESL outbound call"])
+}
+
+func TestConn_Linger_CloseDelay(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.Logger = NilLogger{}
+	connection := newConnection(client, true, opts)
+	defer connection.Close()
+	defer server.Close()
+	go connection.dummyLoop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	serverReader := bufio.NewReader(server)
+	replied := make(chan error, 1)
+	go func() {
+		_, err := connection.SendCommand(ctx, command.Linger{Enabled: true, Timeout: 10 * time.Second})
+		replied <- err
+	}()
+
+	incomingCommand, err := serverReader.ReadString('\r')
+	assert.Nil(t, err)
+	assert.Equal(t, "linger 10\r", incomingCommand)
+	_, err = server.Write([]byte("Content-Type: command/reply\r\nReply-Text: +OK will linger\r\n\r\n"))
+	assert.Nil(t, err)
+	assert.Nil(t, <-replied)
+	assert.Equal(t, 10*time.Second, connection.closeDelay)
+
+	// The disconnect notice should only schedule the close, not close the connection right away
+	_, err = server.Write([]byte("Content-Type: text/disconnect-notice\r\nContent-Length: 0\r\n\r\n"))
+	assert.Nil(t, err)
+	time.Sleep(200 * time.Millisecond)
+	assert.Nil(t, connection.runningContext.Err())
+}
+
+func TestConn_Linger_Validate(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.Logger = NilLogger{}
+	connection := newConnection(client, true, opts)
+	defer connection.Close()
+	defer server.Close()
+
+	// Rejected before anything is written, the pipe would block otherwise
+	_, err := connection.SendCommand(context.Background(), command.Linger{Enabled: true, Timeout: 100 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Equal(t, time.Duration(0), connection.closeDelay)
+}
@@ -27,6 +27,9 @@ const (
 	TypeAPIResponse = `api/response`
 	TypeAuthRequest = `auth/request`
 	TypeDisconnect  = `text/disconnect-notice`
+	TypeLogData     = `log/data`
+	// TypeRudeRejection - Sent instead of auth/request when our address is not allowed by the event_socket ACL
+	TypeRudeRejection = `text/rude-rejection`
 )
 
 // RawResponse This struct contains all response data from FreeSWITCH
@@ -56,6 +59,9 @@ func (c *Conn) readResponse() (*RawResponse, error) {
 		}
 	}
 
+	if c.recorder != nil {
+		c.recorder.received(response)
+	}
 	return response, nil
 }
 
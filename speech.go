@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// SpeechResult - A speech recognition result parsed from a DETECTED_SPEECH event or the detect_speech_result variable
+type SpeechResult struct {
+	Grammar         string                 // The grammar that matched, if reported
+	Interpretations []SpeechInterpretation // Possible interpretations, usually ordered best first
+	NoMatch         bool                   // The caller said something that did not match any grammar
+	NoInput         bool                   // The caller did not say anything
+	Raw             string                 // The unparsed result
+}
+
+// SpeechInterpretation - A single possible interpretation of what the caller said
+type SpeechInterpretation struct {
+	Grammar    string  // The grammar that produced the interpretation, if reported
+	Confidence float64 // Confidence between 0 and 1. Engines reporting 0-100 are scaled down
+	Instance   string  // The semantic interpretation, e.g. "yes" for "yeah sure"
+	Input      string  // What the caller said
+	Mode       string  // speech or dtmf, if reported
+}
+
+// Best - Returns the interpretation with the highest confidence
+func (r SpeechResult) Best() (SpeechInterpretation, bool) {
+	if len(r.Interpretations) == 0 {
+		return SpeechInterpretation{}, false
+	}
+	best := r.Interpretations[0]
+	for _, interpretation := range r.Interpretations[1:] {
+		if interpretation.Confidence > best.Confidence {
+			best = interpretation
+		}
+	}
+	return best, true
+}
+
+// Text - Returns the semantic interpretation of the best result, falling back to what the caller said
+func (r SpeechResult) Text() string {
+	best, _ := r.Best()
+	if len(best.Instance) > 0 {
+		return best.Instance
+	}
+	return best.Input
+}
+
+// SpeechResult Helper to parse the body of DETECTED_SPEECH events. Returns nil without an error for other speech types such as begin-speaking
+func (e Event) SpeechResult() (*SpeechResult, error) {
+	if e.GetHeader("Speech-Type") != "detected-speech" {
+		return nil, nil
+	}
+	return ParseSpeechResult(e.Body)
+}
+
+// ParseSpeechResult - Parses an NLSML(XML) or JSON speech recognition result
+func ParseSpeechResult(body []byte) (*SpeechResult, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, errors.New("empty speech result")
+	}
+	if trimmed[0] == '{' || trimmed[0] == '[' {
+		return parseJSONSpeechResult(trimmed)
+	}
+	return parseNLSMLSpeechResult(trimmed)
+}
+
+type nlsmlResult struct {
+	Grammar         string `xml:"grammar,attr"`
+	Interpretations []struct {
+		Grammar    string `xml:"grammar,attr"`
+		Confidence string `xml:"confidence,attr"`
+		Instance   struct {
+			Inner string `xml:",innerxml"`
+		} `xml:"instance"`
+		Input struct {
+			Mode    string    `xml:"mode,attr"`
+			Text    string    `xml:",chardata"`
+			NoMatch *struct{} `xml:"nomatch"`
+			NoInput *struct{} `xml:"noinput"`
+		} `xml:"input"`
+	} `xml:"interpretation"`
+}
+
+func parseNLSMLSpeechResult(body []byte) (*SpeechResult, error) {
+	var parsed nlsmlResult
+	if err := xml.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+
+	result := &SpeechResult{
+		Grammar: parsed.Grammar,
+		Raw:     string(body),
+	}
+	for _, interpretation := range parsed.Interpretations {
+		if interpretation.Input.NoMatch != nil {
+			result.NoMatch = true
+			continue
+		}
+		if interpretation.Input.NoInput != nil {
+			result.NoInput = true
+			continue
+		}
+		result.Interpretations = append(result.Interpretations, SpeechInterpretation{
+			Grammar:    interpretation.Grammar,
+			Confidence: parseConfidence(interpretation.Confidence),
+			Instance:   strings.TrimSpace(interpretation.Instance.Inner),
+			Input:      strings.TrimSpace(interpretation.Input.Text),
+			Mode:       interpretation.Input.Mode,
+		})
+	}
+	if len(result.Grammar) == 0 && len(result.Interpretations) > 0 {
+		result.Grammar = result.Interpretations[0].Grammar
+	}
+	return result, nil
+}
+
+// jsonSpeechResult - The common shapes engines use for JSON results, either a single result or a list of alternatives
+type jsonSpeechResult struct {
+	Grammar      string             `json:"grammar"`
+	Text         string             `json:"text"`
+	Transcript   string             `json:"transcript"`
+	Input        string             `json:"input"`
+	Instance     json.RawMessage    `json:"instance"`
+	Confidence   json.Number        `json:"confidence"`
+	Mode         string             `json:"mode"`
+	NoMatch      bool               `json:"nomatch"`
+	NoInput      bool               `json:"noinput"`
+	Alternatives []jsonSpeechResult `json:"alternatives"`
+	Results      []jsonSpeechResult `json:"interpretations"`
+}
+
+func parseJSONSpeechResult(body []byte) (*SpeechResult, error) {
+	var parsed jsonSpeechResult
+	if body[0] == '[' {
+		if err := json.Unmarshal(body, &parsed.Alternatives); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, err
+	}
+
+	result := &SpeechResult{
+		Grammar: parsed.Grammar,
+		NoMatch: parsed.NoMatch,
+		NoInput: parsed.NoInput,
+		Raw:     string(body),
+	}
+	alternatives := append(parsed.Alternatives, parsed.Results...)
+	if len(alternatives) == 0 && len(parsed.text()) > 0 {
+		alternatives = []jsonSpeechResult{parsed}
+	}
+	for _, alternative := range alternatives {
+		grammar := alternative.Grammar
+		if len(grammar) == 0 {
+			grammar = parsed.Grammar
+		}
+		result.Interpretations = append(result.Interpretations, SpeechInterpretation{
+			Grammar:    grammar,
+			Confidence: parseConfidence(alternative.Confidence.String()),
+			Instance:   alternative.instance(),
+			Input:      alternative.text(),
+			Mode:       alternative.Mode,
+		})
+	}
+	return result, nil
+}
+
+func (j jsonSpeechResult) text() string {
+	for _, text := range []string{j.Input, j.Text, j.Transcript} {
+		if len(text) > 0 {
+			return text
+		}
+	}
+	return ""
+}
+
+// instance - Semantic interpretations can be a string or an object, objects are kept as JSON
+func (j jsonSpeechResult) instance() string {
+	var instance string
+	if err := json.Unmarshal(j.Instance, &instance); err == nil {
+		return instance
+	}
+	return string(j.Instance)
+}
+
+// parseConfidence - Parses a confidence, scaling 0-100 values to 0-1
+func parseConfidence(confidence string) float64 {
+	value, err := strconv.ParseFloat(strings.TrimSpace(confidence), 64)
+	if err != nil {
+		return 0
+	}
+	if value > 1 {
+		value /= 100
+	}
+	return value
+}
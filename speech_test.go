@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testNLSMLResult = `<?xml version="1.0"?>
+<result grammar="yesno">
+  <interpretation grammar="yesno" confidence="87">
+    <instance>yes</instance>
+    <input mode="speech">yeah sure</input>
+  </interpretation>
+  <interpretation grammar="yesno" confidence="12">
+    <instance>no</instance>
+    <input mode="speech">nah sure</input>
+  </interpretation>
+</result>`
+
+func TestParseSpeechResult_NLSML(t *testing.T) {
+	result, err := ParseSpeechResult([]byte(testNLSMLResult))
+	assert.Nil(t, err)
+	assert.Equal(t, "yesno", result.Grammar)
+	assert.Len(t, result.Interpretations, 2)
+	best, ok := result.Best()
+	assert.True(t, ok)
+	assert.Equal(t, SpeechInterpretation{
+		Grammar:    "yesno",
+		Confidence: 0.87,
+		Instance:   "yes",
+		Input:      "yeah sure",
+		Mode:       "speech",
+	}, best)
+	assert.Equal(t, "yes", result.Text())
+
+	result, err = ParseSpeechResult([]byte(`<result><interpretation><input><nomatch/></input></interpretation></result>`))
+	assert.Nil(t, err)
+	assert.True(t, result.NoMatch)
+	assert.Empty(t, result.Interpretations)
+
+	_, err = ParseSpeechResult([]byte("<result>"))
+	assert.NotNil(t, err)
+}
+
+func TestParseSpeechResult_JSON(t *testing.T) {
+	result, err := ParseSpeechResult([]byte(`{"text": "book a flight", "confidence": 0.92}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "book a flight", result.Text())
+	best, _ := result.Best()
+	assert.Equal(t, 0.92, best.Confidence)
+
+	result, err = ParseSpeechResult([]byte(`{"grammar": "menu", "alternatives": [
+		{"transcript": "sales", "confidence": 40},
+		{"transcript": "support", "instance": {"department": 2}, "confidence": 75}
+	]}`))
+	assert.Nil(t, err)
+	best, _ = result.Best()
+	assert.Equal(t, "menu", best.Grammar)
+	assert.Equal(t, "support", best.Input)
+	assert.Equal(t, `{"department": 2}`, best.Instance)
+	assert.Equal(t, 0.75, best.Confidence)
+
+	result, err = ParseSpeechResult([]byte(`{"noinput": true}`))
+	assert.Nil(t, err)
+	assert.True(t, result.NoInput)
+	_, ok := result.Best()
+	assert.False(t, ok)
+}
+
+func TestConn_WaitForSpeech(t *testing.T) {
+	conn, commands := dialTestServer(t, func(command string) string {
+		begin := "Event-Name: DETECTED_SPEECH\nUnique-ID: channel\nSpeech-Type: begin-speaking\n\n"
+		detected := fmt.Sprintf("Event-Name: DETECTED_SPEECH\nUnique-ID: channel\nSpeech-Type: detected-speech\nContent-Length: %d\n\n%s", len(testNLSMLResult), testNLSMLResult)
+		return testReply("+OK") +
+			fmt.Sprintf("Content-Length: %d\r\nContent-Type: text/event-plain\r\n\r\n%s", len(begin), begin) +
+			fmt.Sprintf("Content-Length: %d\r\nContent-Type: text/event-plain\r\n\r\n%s", len(detected), detected)
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	results := make(chan *SpeechResult, 1)
+	go func() {
+		result, err := conn.WaitForSpeech(ctx, "channel")
+		assert.Nil(t, err)
+		results <- result
+	}()
+	// Give the listener time to register before the events are sent
+	time.Sleep(50 * time.Millisecond)
+	assert.Nil(t, conn.DetectSpeech(ctx, "channel", "unimrcp", SpeechGrammar{Name: "yesno", Path: "builtin:grammar/boolean"}))
+
+	select {
+	case result := <-results:
+		assert.Equal(t, "yes", result.Text())
+	case <-ctx.Done():
+		t.Fatal("no speech result")
+	}
+	assert.True(t, strings.HasPrefix(commands()[0], "sendmsg channel"))
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"context"
+	"strings"
+)
+
+// Span attribute keys set by eslgo
+const (
+	TraceKeyCommand     = "esl.command"
+	TraceKeyChannelUUID = "esl.channel_uuid"
+	TraceKeyReply       = "esl.reply"
+	TraceKeyLatency     = "esl.latency_ms"
+	TraceKeyDestination = "esl.destination_number"
+	TraceKeyDirection   = "esl.direction"
+)
+
+// SpanKind - The role of eslgo in a traced operation
+type SpanKind int
+
+const (
+	// SpanKindClient - A command we send to FreeSWITCH
+	SpanKindClient SpanKind = iota
+	// SpanKindServer - An outbound call FreeSWITCH connected to us for, handled by our OutboundHandler
+	SpanKindServer
+)
+
+// Tracer - An optional hook for tracing commands and outbound call handling. See the tracing/otel package for an OpenTelemetry implementation.
+type Tracer interface {
+	// Start - Starts a new span of the kind as a child of any span in ctx. Returns the context containing the new span
+	Start(ctx context.Context, name string, kind SpanKind) (context.Context, Span)
+}
+
+// Span - A single traced operation started by a Tracer
+type Span interface {
+	// SetAttribute - Sets an attribute on the span, value is a string, bool, int, int64 or float64
+	SetAttribute(key string, value interface{})
+	// AddEvent - Records a named event on the span with the provided attributes
+	AddEvent(name string, attributes map[string]string)
+	// RecordError - Records the error on the span and marks it as failed
+	RecordError(err error)
+	// End - Completes the span
+	End()
+}
+
+// NilTracer - A Tracer implementation that discards everything, used when Options.Tracer is nil
+type NilTracer struct{}
+
+// NilSpan - The Span returned by NilTracer
+type NilSpan struct{}
+
+func (NilTracer) Start(ctx context.Context, _ string, _ SpanKind) (context.Context, Span) {
+	return ctx, NilSpan{}
+}
+
+func (NilSpan) SetAttribute(string, interface{})   {}
+func (NilSpan) AddEvent(string, map[string]string) {}
+func (NilSpan) RecordError(error)                  {}
+func (NilSpan) End()                               {}
+
+// TraceChannelEvents - Adds every event for the channel UUID to the span as a span event. Requires events to be enabled!
+// The listener is ordered, so every event is added before other listeners see it and before later events are handled.
+// Returns the registered listener ID, remove it with RemoveEventListener once the span ends.
+func (c *Conn) TraceChannelEvents(span Span, channelUUID string) string {
+	return c.RegisterOrderedEventListener(channelUUID, func(event *Event) {
+		attributes := map[string]string{
+			"Unique-ID": event.GetHeader("Unique-ID"),
+		}
+		for _, header := range []string{"Event-Date-Timestamp", "Event-Subclass", "Application", "Application-Data", "Hangup-Cause", "Answer-State"} {
+			if event.HasHeader(header) {
+				attributes[header] = event.GetHeader(header)
+			}
+		}
+		span.AddEvent(event.GetName(), attributes)
+	})
+}
+
+// commandChannelUUID - Returns the channel UUID targeted by a built command message if there is one
+func commandChannelUUID(message string) string {
+	line := message
+	if end := strings.IndexAny(line, "\r\n"); end >= 0 {
+		line = line[:end]
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return ""
+	}
+
+	switch fields[0] {
+	case "sendmsg", "myevents":
+		return fields[1]
+	case "api", "bgapi":
+		// Most uuid_ API commands take the channel UUID as their first argument
+		if strings.HasPrefix(fields[1], "uuid_") && len(fields) > 2 {
+			return fields[2]
+		}
+	}
+	return ""
+}
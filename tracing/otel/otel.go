@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package otel
+
+import (
+	"context"
+	"fmt"
+	"github.com/percipia/eslgo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer - Implements eslgo.Tracer using an OpenTelemetry trace.Tracer
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// Span - Implements eslgo.Span using an OpenTelemetry trace.Span
+type Span struct {
+	span trace.Span
+}
+
+var _ eslgo.Tracer = Tracer{}
+var _ eslgo.Span = Span{}
+
+// NewTracer - Creates an eslgo.Tracer from an OpenTelemetry trace.TracerProvider, e.g. otel.GetTracerProvider()
+func NewTracer(provider trace.TracerProvider) Tracer {
+	return Tracer{tracer: provider.Tracer("github.com/percipia/eslgo")}
+}
+
+func (t Tracer) Start(ctx context.Context, name string, kind eslgo.SpanKind) (context.Context, eslgo.Span) {
+	spanKind := trace.SpanKindClient
+	if kind == eslgo.SpanKindServer {
+		spanKind = trace.SpanKindServer
+	}
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(spanKind))
+	return ctx, Span{span: span}
+}
+
+func (s Span) SetAttribute(key string, value interface{}) {
+	s.span.SetAttributes(toAttribute(key, value))
+}
+
+func (s Span) AddEvent(name string, attributes map[string]string) {
+	attrs := make([]attribute.KeyValue, 0, len(attributes))
+	for key, value := range attributes {
+		attrs = append(attrs, attribute.String(key, value))
+	}
+	s.span.AddEvent(name, trace.WithAttributes(attrs...))
+}
+
+func (s Span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s Span) End() {
+	s.span.End()
+}
+
+func toAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	default:
+		return attribute.String(key, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package otel
+
+import (
+	"context"
+	"errors"
+	"github.com/percipia/eslgo"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := NewTracer(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, parent := tracer.Start(context.Background(), "ESL outbound call", eslgo.SpanKindServer)
+	parent.SetAttribute("esl.channel_uuid", "a1b2")
+	parent.AddEvent("CHANNEL_ANSWER", map[string]string{"Unique-ID": "a1b2"})
+	_, child := tracer.Start(ctx, "ESL api", eslgo.SpanKindClient)
+	child.SetAttribute("esl.latency_ms", int64(12))
+	child.RecordError(errors.New("-ERR no such channel"))
+	child.End()
+	parent.End()
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "ESL api", spans[0].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Contains(t, spans[0].Attributes(), attribute.Int64("esl.latency_ms", 12))
+	assert.Contains(t, spans[1].Attributes(), attribute.String("esl.channel_uuid", "a1b2"))
+	assert.Equal(t, "CHANNEL_ANSWER", spans[1].Events()[0].Name)
+	assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	assert.Equal(t, trace.SpanKindServer, spans[1].SpanKind())
+}
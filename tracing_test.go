@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"context"
+	"github.com/percipia/eslgo/command"
+	"github.com/percipia/eslgo/command/call"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSpan struct {
+	mutex      sync.Mutex
+	name       string
+	kind       SpanKind
+	attributes map[string]interface{}
+	errors     []error
+	events     []string
+	ended      bool
+}
+
+func (s *recordingSpan) SetAttribute(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attributes[key] = value
+}
+func (s *recordingSpan) AddEvent(name string, _ map[string]string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = append(s.events, name)
+}
+func (s *recordingSpan) RecordError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.errors = append(s.errors, err)
+}
+func (s *recordingSpan) End() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ended = true
+}
+
+type recordingTracer struct {
+	spans []*recordingSpan
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, Span) {
+	span := &recordingSpan{name: name, kind: kind, attributes: make(map[string]interface{})}
+	r.spans = append(r.spans, span)
+	return ctx, span
+}
+
+func Test_commandChannelUUID(t *testing.T) {
+	assert.Equal(t, "a1b2", commandChannelUUID((&call.Execute{UUID: "a1b2", AppName: "answer"}).BuildMessage()))
+	assert.Equal(t, "a1b2", commandChannelUUID(command.API{Command: "uuid_kill", Arguments: "a1b2 NORMAL_CLEARING"}.BuildMessage()))
+	assert.Equal(t, "", commandChannelUUID(command.API{Command: "status"}.BuildMessage()))
+	assert.Equal(t, "", commandChannelUUID(command.Exit{}.BuildMessage()))
+}
+
+func TestConn_SendCommand_Tracing(t *testing.T) {
+	server, client := net.Pipe()
+	tracer := &recordingTracer{}
+	opts := DefaultOptions
+	opts.Tracer = tracer
+	connection := newConnection(client, false, opts)
+	defer connection.Close()
+	defer server.Close()
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	serverReader := bufio.NewReader(server)
+	go func() {
+		_, _ = serverReader.ReadString('\r')
+		_, _ = server.Write([]byte("Content-Type: api/response\r\nContent-Length: 21\r\n\r\n-ERR No such channel!"))
+	}()
+
+	_, err := connection.SendCommand(ctx, command.API{Command: "uuid_kill", Arguments: "a1b2"})
+	assert.Nil(t, err)
+
+	assert.Len(t, tracer.spans, 1)
+	span := tracer.spans[0]
+	assert.Equal(t, "ESL api", span.name)
+	assert.Equal(t, SpanKindClient, span.kind)
+	assert.Equal(t, "api", span.attributes[TraceKeyCommand])
+	assert.Equal(t, "a1b2", span.attributes[TraceKeyChannelUUID])
+	assert.Equal(t, "-ERR No such channel!", span.attributes[TraceKeyReply])
+	assert.Len(t, span.errors, 1)
+	assert.True(t, span.ended)
+}
+
+func TestConn_TraceChannelEvents(t *testing.T) {
+	server, client := net.Pipe()
+	opts := DefaultOptions
+	opts.Logger = NilLogger{}
+	connection := newConnection(client, false, opts)
+	defer connection.Close()
+	defer server.Close()
+
+	span := &recordingSpan{attributes: make(map[string]interface{})}
+	id := connection.TraceChannelEvents(span, "channel")
+	hungUp := make(chan struct{})
+	connection.RegisterEventListener("channel", func(event *Event) {
+		if event.GetName() == "CHANNEL_HANGUP_COMPLETE" {
+			close(hungUp)
+		}
+	})
+
+	_, err := server.Write([]byte(testEvent("Event-Name: CHANNEL_ANSWER", "Unique-ID: channel") + testEvent("Event-Name: CHANNEL_HANGUP_COMPLETE", "Unique-ID: channel")))
+	assert.Nil(t, err)
+	select {
+	case <-hungUp:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hangup not received")
+	}
+	// Every event seen by other listeners is already on the span, so ending it here loses nothing
+	connection.RemoveEventListener("channel", id)
+	span.End()
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	assert.Equal(t, []string{"CHANNEL_ANSWER", "CHANNEL_HANGUP_COMPLETE"}, span.events)
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Traffic files start with a comment line and contain one frame per message sent or received:
+//
+//	# eslgo traffic v1
+//	> 2024-01-02T15:04:05.123456789Z 14
+//	api status
+//
+//
+//	< 2024-01-02T15:04:05.125Z 49
+//	Content-Length: 3
+//	Content-Type: api/response
+//
+//	+OK
+//
+// Each frame starts with a line holding the direction, > for sent by us and < for received from FreeSWITCH, the RFC 3339 time
+// and the length of the data in bytes. The data follows as is, then a newline that is not part of the data. Lines starting with #
+// between frames are comments. Passwords of auth and userauth commands are replaced with ********. Received frames are rebuilt
+// from the parsed message with the headers sorted, so the header order and line endings FreeSWITCH used are not kept.
+
+// Traffic directions
+const (
+	TrafficSent     = ">"
+	TrafficReceived = "<"
+)
+
+// trafficRedacted - Written instead of passwords
+const trafficRedacted = "********"
+
+// trafficHeader - The comment line written at the start of every traffic file
+const trafficHeader = "# eslgo traffic v1\n"
+
+// TrafficFrame - A single message sent or received, as recorded by a TrafficRecorder
+type TrafficFrame struct {
+	Direction string // TrafficSent or TrafficReceived
+	Time      time.Time
+	Data      []byte
+}
+
+// TrafficRecorder - Writes all ESL traffic of the connections using it to a writer. Set it as Options.Recorder
+type TrafficRecorder struct {
+	lock   sync.Mutex
+	writer io.Writer
+	err    error
+	now    func() time.Time
+}
+
+// Replayer - Plays a recorded session back as FreeSWITCH, to reproduce bugs without a FreeSWITCH server
+type Replayer struct {
+	Frames []TrafficFrame
+	Speed  float64 // 1 replays at the original speed, 2 twice as fast. 0 replays as fast as possible
+}
+
+// NewTrafficRecorder - Creates a recorder writing to the writer. The writer is not closed by the recorder
+func NewTrafficRecorder(writer io.Writer) *TrafficRecorder {
+	recorder := &TrafficRecorder{writer: writer, now: time.Now}
+	_, recorder.err = io.WriteString(writer, trafficHeader)
+	return recorder
+}
+
+// Err - Returns the first error writing to the writer, once a write fails nothing else is recorded
+func (r *TrafficRecorder) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+func (r *TrafficRecorder) sent(message string) {
+	r.write(TrafficSent, []byte(redactCommand(message)))
+}
+
+func (r *TrafficRecorder) received(response *RawResponse) {
+	r.write(TrafficReceived, response.raw())
+}
+
+func (r *TrafficRecorder) write(direction string, data []byte) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return
+	}
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "%s %s %d\n", direction, r.now().UTC().Format(time.RFC3339Nano), len(data))
+	buffer.Write(data)
+	buffer.WriteByte('\n')
+	_, r.err = r.writer.Write(buffer.Bytes())
+}
+
+// redactCommand - Replaces the password of auth and userauth commands so traffic files can be shared safely
+func redactCommand(message string) string {
+	line, rest, _ := strings.Cut(message, "\r\n")
+	switch commandName(line) {
+	case "auth":
+		line = "auth " + trafficRedacted
+	case "userauth":
+		// userauth user@domain:password, the user name is kept
+		if user, _, ok := strings.Cut(line, ":"); ok {
+			line = user + ":" + trafficRedacted
+		}
+	default:
+		return message
+	}
+	return line + "\r\n" + rest
+}
+
+// raw - Rebuilds the message as FreeSWITCH sent it, with the headers sorted
+func (r RawResponse) raw() []byte {
+	var buffer bytes.Buffer
+	keys := make([]string, 0, len(r.Headers))
+	for key := range r.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range r.Headers[key] {
+			buffer.WriteString(key + ": " + value + "\n")
+		}
+	}
+	buffer.WriteByte('\n')
+	buffer.Write(r.Body)
+	return buffer.Bytes()
+}
+
+// ReadTraffic - Reads all frames of a traffic file written by a TrafficRecorder
+func ReadTraffic(reader io.Reader) ([]TrafficFrame, error) {
+	buffered := bufio.NewReader(reader)
+	var frames []TrafficFrame
+	for {
+		line, err := buffered.ReadString('\n')
+		if err == io.EOF && len(line) == 0 {
+			return frames, nil
+		} else if err != nil {
+			return frames, err
+		}
+		line = strings.TrimSpace(line)
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 3 || (fields[0] != TrafficSent && fields[0] != TrafficReceived) {
+			return frames, fmt.Errorf("invalid traffic frame: %q", line)
+		}
+		frameTime, err := time.Parse(time.RFC3339Nano, fields[1])
+		if err != nil {
+			return frames, fmt.Errorf("invalid traffic frame time: %w", err)
+		}
+		length, err := strconv.Atoi(fields[2])
+		if err != nil || length < 0 {
+			return frames, fmt.Errorf("invalid traffic frame length: %q", fields[2])
+		}
+		frame := TrafficFrame{Direction: fields[0], Time: frameTime, Data: make([]byte, length)}
+		if _, err = io.ReadFull(buffered, frame.Data); err != nil {
+			return frames, fmt.Errorf("truncated traffic frame: %w", err)
+		}
+		// The newline ending the frame, missing at the end of a file that was cut off
+		if _, err = buffered.ReadByte(); err != nil && err != io.EOF {
+			return frames, err
+		}
+		frames = append(frames, frame)
+	}
+}
+
+// NewReplayer - Reads the traffic file to replay at the speed
+func NewReplayer(reader io.Reader, speed float64) (*Replayer, error) {
+	frames, err := ReadTraffic(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{Frames: frames, Speed: speed}, nil
+}
+
+// ServeListener - Accepts a single connection, e.g. from Dial, and replays the session to it. The listener is not closed
+func (r *Replayer) ServeListener(ctx context.Context, listener net.Listener) error {
+	conn, err := listener.Accept()
+	if err != nil {
+		return err
+	}
+	return r.Serve(ctx, conn)
+}
+
+// Serve - Replays the session over the connection and closes it once done. Received frames are written at their recorded time,
+// sent frames wait for the next command from the connection but the command is not compared to the recorded one.
+// To replay an outbound session dial the outbound server and pass the connection
+func (r *Replayer) Serve(ctx context.Context, conn net.Conn) error {
+	defer conn.Close()
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	// Unblock reads and writes once the context is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	var previous time.Time
+	for i, frame := range r.Frames {
+		if i > 0 && r.Speed > 0 {
+			if err := sleepContext(ctx, time.Duration(float64(frame.Time.Sub(previous))/r.Speed)); err != nil {
+				return err
+			}
+		}
+		previous = frame.Time
+
+		var err error
+		if frame.Direction == TrafficSent {
+			err = readCommand(reader)
+		} else {
+			_, err = conn.Write(frame.Data)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return fmt.Errorf("replaying frame %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// readCommand - Reads a whole command including any headers and body
+func readCommand(reader *textproto.Reader) error {
+	if _, err := reader.ReadLine(); err != nil {
+		return err
+	}
+	headers, err := reader.ReadMIMEHeader()
+	if err != nil {
+		return err
+	}
+	if length, _ := strconv.Atoi(headers.Get("Content-Length")); length > 0 {
+		_, err = io.CopyN(io.Discard, reader.R, int64(length))
+	}
+	return err
+}
+
+func sleepContext(ctx context.Context, duration time.Duration) error {
+	if duration <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
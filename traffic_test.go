@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package eslgo
+
+import (
+	"bytes"
+	"context"
+	"github.com/percipia/eslgo/command"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTrafficRecorder(t *testing.T) {
+	var buffer bytes.Buffer
+	recorder := NewTrafficRecorder(&buffer)
+	recorder.now = func() time.Time {
+		return time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	}
+	recorder.sent("api status" + EndOfMessage)
+	recorder.received(&RawResponse{
+		Headers: map[string][]string{"Content-Type": {"api/response"}, "Content-Length": {"3"}},
+		Body:    []byte("+OK"),
+	})
+	assert.Nil(t, recorder.Err())
+	assert.Equal(t, "# eslgo traffic v1\n"+
+		"> 2024-01-02T15:04:05Z 14\napi status\r\n\r\n\n"+
+		"< 2024-01-02T15:04:05Z 49\nContent-Length: 3\nContent-Type: api/response\n\n+OK\n", buffer.String())
+
+	frames, err := ReadTraffic(&buffer)
+	assert.Nil(t, err)
+	assert.Equal(t, []TrafficFrame{
+		{Direction: TrafficSent, Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Data: []byte("api status\r\n\r\n")},
+		{Direction: TrafficReceived, Time: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC), Data: []byte("Content-Length: 3\nContent-Type: api/response\n\n+OK")},
+	}, frames)
+
+	_, err = ReadTraffic(strings.NewReader("> yesterday 3\nabc\n"))
+	assert.Error(t, err)
+	_, err = ReadTraffic(strings.NewReader("< 2024-01-02T15:04:05Z 30\nabc\n"))
+	assert.Error(t, err)
+}
+
+func TestReplayer(t *testing.T) {
+	// Record a session with a fake server
+	server := newTestServer(t, func(command string) string {
+		if strings.HasPrefix(command, "auth") {
+			return testReply("+OK accepted")
+		}
+		return testAPIResponse("UP 0 years, 0 days") + testEvent("Event-Name: HEARTBEAT", "Unique-ID: channel")
+	})
+	var recording bytes.Buffer
+	opts := DefaultInboundOptions
+	opts.Logger = NilLogger{}
+	opts.ExitTimeout = 100 * time.Millisecond
+	opts.Recorder = NewTrafficRecorder(&recording)
+	conn, err := opts.Dial(server.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	events, remove := conn.awaitEvent("channel", "HEARTBEAT")
+	response, err := conn.SendCommand(ctx, command.API{Command: "status"})
+	assert.Nil(t, err)
+	assert.Equal(t, "UP 0 years, 0 days", string(response.Body))
+	<-events
+	remove()
+	conn.Close()
+	assert.Nil(t, opts.Recorder.Err())
+
+	// Replay it to a new connection without the fake server
+	replayer, err := NewReplayer(&recording, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every reply is recorded after the command it answers
+	var directions []string
+	for _, frame := range replayer.Frames {
+		directions = append(directions, frame.Direction)
+	}
+	assert.Equal(t, []string{TrafficReceived, TrafficSent, TrafficReceived, TrafficSent, TrafficReceived, TrafficReceived}, directions)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	replayed := make(chan error, 1)
+	go func() {
+		replayed <- replayer.ServeListener(ctx, listener)
+	}()
+
+	opts.Recorder = nil
+	opts.Password = "not the recorded password"
+	conn, err = opts.Dial(listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	events, remove = conn.awaitEvent("channel", "HEARTBEAT")
+	defer remove()
+	response, err = conn.SendCommand(ctx, command.API{Command: "status"})
+	assert.Nil(t, err)
+	assert.Equal(t, "UP 0 years, 0 days", string(response.Body))
+	select {
+	case event := <-events:
+		assert.Equal(t, "HEARTBEAT", event.GetName())
+	case <-ctx.Done():
+		t.Fatal("replayed event not received")
+	}
+	assert.Nil(t, <-replayed)
+}
+
+func TestTrafficRecorder_Redact(t *testing.T) {
+	var buffer bytes.Buffer
+	recorder := NewTrafficRecorder(&buffer)
+	recorder.sent(command.Auth{Password: "ClueCon"}.BuildMessage() + EndOfMessage)
+	recorder.sent(command.Auth{User: "1000", Domain: "example.com", Password: "secret:1"}.BuildMessage() + EndOfMessage)
+	recorder.sent("api status" + EndOfMessage)
+
+	frames, err := ReadTraffic(&buffer)
+	assert.Nil(t, err)
+	if assert.Len(t, frames, 3) {
+		assert.Equal(t, "auth ********\r\n\r\n", string(frames[0].Data))
+		assert.Equal(t, "userauth 1000@example.com:********\r\n\r\n", string(frames[1].Data))
+		assert.Equal(t, "api status\r\n\r\n", string(frames[2].Data))
+	}
+}
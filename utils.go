@@ -12,30 +12,111 @@ package eslgo
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 )
 
+// varDelimiters - Delimiters tried in order when a variable contains a comma, FreeSWITCH switches to them with the ^^ prefix
+const varDelimiters = ":;~!#%&*@"
+
 // BuildVars - A helper that builds channel variable strings to be included in various commands to FreeSWITCH
+// Variables are sorted by name and escaped, if any value contains a comma the delimiter is switched using the ^^ syntax e.g. {^^:a=1,2:b=3}
 func BuildVars(format string, vars map[string]string) string {
 	// No vars do not format
 	if vars == nil || len(vars) == 0 {
 		return ""
 	}
 
+	keys := sortedKeys(vars)
+	delimiter := varDelimiter(vars)
 	var builder strings.Builder
-	for key, value := range vars {
-		if builder.Len() > 0 {
-			builder.WriteString(",")
+	if delimiter != ',' {
+		builder.WriteString("^^")
+		builder.WriteRune(delimiter)
+	}
+	for i, key := range keys {
+		if i > 0 {
+			builder.WriteRune(delimiter)
 		}
 		builder.WriteString(key)
 		builder.WriteString("=")
-		if strings.ContainsAny(value, " ") {
-			builder.WriteString("'")
-			builder.WriteString(value)
-			builder.WriteString("'")
-		} else {
-			builder.WriteString(value)
-		}
+		builder.WriteString(escapeVar(vars[key], delimiter))
 	}
 	return fmt.Sprintf(format, builder.String())
 }
+
+// sortedKeys - Returns the keys of the variables sorted so they are always sent in the same order
+func sortedKeys(vars map[string]string) []string {
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// varDelimiter - Picks the first delimiter that does not appear in any of the variables
+func varDelimiter(vars map[string]string) rune {
+	contains := func(delimiter rune) bool {
+		for key, value := range vars {
+			if strings.ContainsRune(key, delimiter) || strings.ContainsRune(value, delimiter) {
+				return true
+			}
+		}
+		return false
+	}
+	if !contains(',') {
+		return ','
+	}
+	for _, delimiter := range varDelimiters {
+		if !contains(delimiter) {
+			return delimiter
+		}
+	}
+	// Nothing left to switch to, fall back to the default. escapeVar quotes the values containing commas
+	return ','
+}
+
+// escapeVar - Wraps values containing spaces, quotes, backslashes or the delimiter in single quotes, escaping quotes and backslashes inside
+func escapeVar(value string, delimiter rune) string {
+	if !strings.ContainsAny(value, " '\\") && !strings.ContainsRune(value, delimiter) {
+		return value
+	}
+	return quoteVar(value)
+}
+
+// quoteVar - Wraps the value in single quotes, escaping quotes and backslashes inside
+func quoteVar(value string) string {
+	var builder strings.Builder
+	builder.WriteString("'")
+	for _, r := range value {
+		if r == '\'' || r == '\\' {
+			builder.WriteRune('\\')
+		}
+		builder.WriteRune(r)
+	}
+	builder.WriteString("'")
+	return builder.String()
+}
+
+// unescapeVar - Reverses escapeVar, removing quotes and backslash escapes
+func unescapeVar(value string) string {
+	if !strings.ContainsAny(value, "'\\") {
+		return value
+	}
+	var builder strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			builder.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '\'':
+		default:
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
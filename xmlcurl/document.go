@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package xmlcurl
+
+import (
+	"encoding/xml"
+	"github.com/percipia/eslgo"
+	"sort"
+)
+
+// Param - A param element, used for directory and configuration settings
+type Param struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// Variable - A variable element, set as a channel variable on calls involving the user or domain
+type Variable struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// Params - A list of param elements, the wrapping element is left out when empty
+type Params []Param
+
+// Variables - A list of variable elements, the wrapping element is left out when empty
+type Variables []Variable
+
+// Domain - A directory domain with the users FreeSWITCH asked for
+type Domain struct {
+	Name      string    `xml:"name,attr"`
+	Params    Params    `xml:"params,omitempty"`
+	Variables Variables `xml:"variables,omitempty"`
+	Users     []User    `xml:"users>user"`
+}
+
+// User - A directory user, the password param is used for SIP registration and userauth
+type User struct {
+	ID        string    `xml:"id,attr"`
+	Cacheable string    `xml:"cacheable,attr,omitempty"` // Allows FreeSWITCH to cache the user, true or a number of milliseconds
+	Params    Params    `xml:"params,omitempty"`
+	Variables Variables `xml:"variables,omitempty"`
+}
+
+// DialplanContext - A dialplan context with the extensions for the call
+type DialplanContext struct {
+	Name       string                    `xml:"name,attr"`
+	Extensions []eslgo.DialplanExtension `xml:"extension"`
+}
+
+// Configuration - A configuration document such as sofia.conf or ivr.conf
+// Settings are rendered as the common settings element, InnerXML is added as is for everything else such as sofia profiles
+type Configuration struct {
+	Name        string `xml:"name,attr"`
+	Description string `xml:"description,attr,omitempty"`
+	Settings    Params `xml:"settings,omitempty"`
+	InnerXML    string `xml:",innerxml"`
+}
+
+// document - The freeswitch/xml document returned to mod_xml_curl
+type document struct {
+	XMLName xml.Name `xml:"document"`
+	Type    string   `xml:"type,attr"`
+	Section section  `xml:"section"`
+}
+
+type section struct {
+	Name           string            `xml:"name,attr"`
+	Domains        []Domain          `xml:"domain"`
+	Contexts       []DialplanContext `xml:"context"`
+	Configurations []Configuration   `xml:"configuration"`
+	Result         *result           `xml:"result"`
+}
+
+type result struct {
+	Status string `xml:"status,attr"`
+}
+
+// NewUser - Creates a directory user with the password and variables, variables are sorted by name
+func NewUser(id, password string, vars map[string]string) User {
+	user := User{ID: id}
+	if len(password) > 0 {
+		user.Params = Params{{Name: "password", Value: password}}
+	}
+	user.Variables = NewVariables(vars)
+	return user
+}
+
+// NewParams - Creates params sorted by name so the rendered document is deterministic
+func NewParams(params map[string]string) Params {
+	var list Params
+	for _, name := range sortedKeys(params) {
+		list = append(list, Param{Name: name, Value: params[name]})
+	}
+	return list
+}
+
+// NewVariables - Creates variables sorted by name so the rendered document is deterministic
+func NewVariables(vars map[string]string) Variables {
+	var list Variables
+	for _, name := range sortedKeys(vars) {
+		list = append(list, Variable{Name: name, Value: vars[name]})
+	}
+	return list
+}
+
+// MarshalXML - Implements xml.Marshaler, encoding every param inside the element named by the field
+func (p Params) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	return encoder.EncodeElement(struct {
+		Params []Param `xml:"param"`
+	}{p}, start)
+}
+
+// MarshalXML - Implements xml.Marshaler, encoding every variable inside the element named by the field
+func (v Variables) MarshalXML(encoder *xml.Encoder, start xml.StartElement) error {
+	return encoder.EncodeElement(struct {
+		Variables []Variable `xml:"variable"`
+	}{v}, start)
+}
+
+// notFound - The document telling FreeSWITCH we have nothing, it then falls back to the next binding or the static XML
+func notFound() document {
+	return document{
+		Type: "freeswitch/xml",
+		Section: section{
+			Name:   "result",
+			Result: &result{Status: "not found"},
+		},
+	}
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
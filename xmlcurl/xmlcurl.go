@@ -0,0 +1,216 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package xmlcurl
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/textproto"
+)
+
+// Sections FreeSWITCH requests from mod_xml_curl
+const (
+	SectionDirectory     = "directory"
+	SectionDialplan      = "dialplan"
+	SectionConfiguration = "configuration"
+	SectionPhrases       = "phrases"
+)
+
+// ErrNotFound - Returned by handlers that have nothing for the request, FreeSWITCH is told the document was not found
+var ErrNotFound = errors.New("not found")
+
+// DirectoryHandler - Returns the domains for a directory request. Usually a single domain with the requested user
+type DirectoryHandler func(ctx context.Context, request *Request) ([]Domain, error)
+
+// DialplanHandler - Returns the dialplan context for a call
+type DialplanHandler func(ctx context.Context, request *Request) (*DialplanContext, error)
+
+// ConfigurationHandler - Returns the configuration document FreeSWITCH asked for
+type ConfigurationHandler func(ctx context.Context, request *Request) (*Configuration, error)
+
+// Request - A mod_xml_curl request. The other posted fields, including the channel variables for dialplan requests, are accessed like Event headers
+type Request struct {
+	Section  string // directory, dialplan, configuration or phrases
+	TagName  string // The element searched for e.g. domain or configuration
+	KeyName  string // The attribute searched by e.g. name
+	KeyValue string // The value searched for e.g. the domain or sofia.conf
+	Hostname string // The hostname of the FreeSWITCH server
+	Headers  textproto.MIMEHeader
+}
+
+// Server - An http.Handler answering mod_xml_curl requests. Sections without a handler are answered with not found
+type Server struct {
+	Directory      DirectoryHandler
+	Dialplan       DialplanHandler
+	Configurations map[string]ConfigurationHandler // By configuration name e.g. sofia.conf
+	Logger         *slog.Logger                    // Optional, handler errors are logged here
+}
+
+// ParseRequest - Decodes the form mod_xml_curl posts
+func ParseRequest(r *http.Request) (*Request, error) {
+	if err := r.ParseForm(); err != nil {
+		return nil, err
+	}
+	request := &Request{
+		Section:  r.Form.Get("section"),
+		TagName:  r.Form.Get("tag_name"),
+		KeyName:  r.Form.Get("key_name"),
+		KeyValue: r.Form.Get("key_value"),
+		Hostname: r.Form.Get("hostname"),
+		Headers:  make(textproto.MIMEHeader),
+	}
+	for key, values := range r.Form {
+		for _, value := range values {
+			request.Headers.Add(key, value)
+		}
+	}
+	return request, nil
+}
+
+// HasHeader Helper to check if the Request has a header
+func (r Request) HasHeader(header string) bool {
+	_, ok := r.Headers[textproto.CanonicalMIMEHeaderKey(header)]
+	return ok
+}
+
+// GetHeader Helper function that calls r.Header.Get
+func (r Request) GetHeader(header string) string {
+	return r.Headers.Get(header)
+}
+
+// GetVariable Helper to get a channel variable of dialplan requests, without the variable_ prefix
+func (r Request) GetVariable(name string) string {
+	return r.GetHeader("variable_" + name)
+}
+
+// User - The user of directory requests
+func (r Request) User() string {
+	return r.GetHeader("user")
+}
+
+// Domain - The domain of directory requests
+func (r Request) Domain() string {
+	if domain := r.GetHeader("domain"); len(domain) > 0 {
+		return domain
+	}
+	return r.KeyValue
+}
+
+// Action - What the directory lookup is for e.g. sip_auth, user_call or message-count
+func (r Request) Action() string {
+	return r.GetHeader("action")
+}
+
+// Purpose - Set for directory requests for every domain e.g. gateways or network-list
+func (r Request) Purpose() string {
+	return r.GetHeader("purpose")
+}
+
+// DestinationNumber - The number dialed for dialplan requests
+func (r Request) DestinationNumber() string {
+	return r.GetHeader("Caller-Destination-Number")
+}
+
+// Context - The dialplan context of dialplan requests
+func (r Request) Context() string {
+	return r.GetHeader("Caller-Context")
+}
+
+// ChannelUUID - The channel of dialplan requests
+func (r Request) ChannelUUID() string {
+	return r.GetHeader("Unique-ID")
+}
+
+// ServeHTTP - Implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	request, err := ParseRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	doc, err := s.handle(r.Context(), request)
+	if errors.Is(err, ErrNotFound) {
+		doc, err = notFound(), nil
+	}
+	if err != nil {
+		if s.Logger != nil {
+			s.Logger.Error("Error handling mod_xml_curl request", "section", request.Section, "key_value", request.KeyValue, "error", err)
+		}
+		// FreeSWITCH logs the failure and falls back to the next binding
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = w.Write([]byte(xml.Header))
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	_ = encoder.Encode(doc)
+}
+
+func (s *Server) handle(ctx context.Context, request *Request) (document, error) {
+	doc := document{
+		Type:    "freeswitch/xml",
+		Section: section{Name: request.Section},
+	}
+	switch request.Section {
+	case SectionDirectory:
+		if s.Directory == nil {
+			return doc, ErrNotFound
+		}
+		domains, err := s.Directory(ctx, request)
+		if err != nil {
+			return doc, err
+		}
+		if len(domains) == 0 {
+			return doc, ErrNotFound
+		}
+		doc.Section.Domains = domains
+	case SectionDialplan:
+		if s.Dialplan == nil {
+			return doc, ErrNotFound
+		}
+		dialplanContext, err := s.Dialplan(ctx, request)
+		if err != nil {
+			return doc, err
+		}
+		if dialplanContext == nil || len(dialplanContext.Extensions) == 0 {
+			return doc, ErrNotFound
+		}
+		if len(dialplanContext.Name) == 0 {
+			dialplanContext.Name = request.Context()
+		}
+		doc.Section.Contexts = []DialplanContext{*dialplanContext}
+	case SectionConfiguration:
+		handler, ok := s.Configurations[request.KeyValue]
+		if !ok {
+			return doc, ErrNotFound
+		}
+		configuration, err := handler(ctx, request)
+		if err != nil {
+			return doc, err
+		}
+		if configuration == nil {
+			return doc, ErrNotFound
+		}
+		if len(configuration.Name) == 0 {
+			configuration.Name = request.KeyValue
+		}
+		doc.Section.Configurations = []Configuration{*configuration}
+	default:
+		return doc, ErrNotFound
+	}
+	return doc, nil
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2020 Percipia
+ *
+ * This Source Code Form is subject to the terms of the Mozilla Public
+ * License, v. 2.0. If a copy of the MPL was not distributed with this
+ * file, You can obtain one at https://mozilla.org/MPL/2.0/.
+ *
+ * Contributor(s):
+ * Andrew Querol <aquerol@percipia.com>
+ */
+package xmlcurl
+
+import (
+	"context"
+	"errors"
+	"github.com/percipia/eslgo"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func post(t *testing.T, server *Server, form url.Values) (int, string) {
+	request := httptest.NewRequest(http.MethodPost, "/freeswitch", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	server.ServeHTTP(recorder, request)
+	body, err := io.ReadAll(recorder.Body)
+	assert.Nil(t, err)
+	return recorder.Code, string(body)
+}
+
+const testNotFound = `<?xml version="1.0" encoding="UTF-8"?>
+<document type="freeswitch/xml">
+  <section name="result">
+    <result status="not found"></result>
+  </section>
+</document>`
+
+func TestServer_Directory(t *testing.T) {
+	server := &Server{
+		Directory: func(ctx context.Context, request *Request) ([]Domain, error) {
+			if request.User() != "1000" {
+				return nil, ErrNotFound
+			}
+			return []Domain{{
+				Name:  request.Domain(),
+				Users: []User{NewUser("1000", "secret", map[string]string{"user_context": "default", "effective_caller_id_name": "Front Desk"})},
+			}}, nil
+		},
+	}
+
+	code, body := post(t, server, url.Values{
+		"section":   {"directory"},
+		"tag_name":  {"domain"},
+		"key_name":  {"name"},
+		"key_value": {"example.com"},
+		"user":      {"1000"},
+		"domain":    {"example.com"},
+		"action":    {"sip_auth"},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<document type="freeswitch/xml">
+  <section name="directory">
+    <domain name="example.com">
+      <users>
+        <user id="1000">
+          <params>
+            <param name="password" value="secret"></param>
+          </params>
+          <variables>
+            <variable name="effective_caller_id_name" value="Front Desk"></variable>
+            <variable name="user_context" value="default"></variable>
+          </variables>
+        </user>
+      </users>
+    </domain>
+  </section>
+</document>`, body)
+
+	code, body = post(t, server, url.Values{"section": {"directory"}, "user": {"1001"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, testNotFound, body)
+}
+
+func TestServer_Dialplan(t *testing.T) {
+	server := &Server{
+		Dialplan: func(ctx context.Context, request *Request) (*DialplanContext, error) {
+			assert.Equal(t, "channel", request.ChannelUUID())
+			assert.Equal(t, "1000", request.GetVariable("sip_from_user"))
+			assert.True(t, request.HasHeader("Caller-Destination-Number"))
+			return &DialplanContext{
+				Extensions: []eslgo.DialplanExtension{
+					eslgo.NewInlineDialplan("answer", "", "playback", "/tmp/welcome.wav").Extension(request.DestinationNumber()),
+				},
+			}, nil
+		},
+	}
+
+	code, body := post(t, server, url.Values{
+		"section":                   {"dialplan"},
+		"Unique-ID":                 {"channel"},
+		"Caller-Context":            {"public"},
+		"Caller-Destination-Number": {"5551234"},
+		"variable_sip_from_user":    {"1000"},
+	})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<document type="freeswitch/xml">
+  <section name="dialplan">
+    <context name="public">
+      <extension name="5551234">
+        <condition>
+          <action application="answer"></action>
+          <action application="playback" data="/tmp/welcome.wav"></action>
+        </condition>
+      </extension>
+    </context>
+  </section>
+</document>`, body)
+}
+
+func TestServer_Configuration(t *testing.T) {
+	server := &Server{
+		Configurations: map[string]ConfigurationHandler{
+			"event_socket.conf": func(ctx context.Context, request *Request) (*Configuration, error) {
+				return &Configuration{
+					Description: "Socket Client",
+					Settings:    NewParams(map[string]string{"listen-port": "8021", "password": "ClueCon"}),
+				}, nil
+			},
+			"broken.conf": func(ctx context.Context, request *Request) (*Configuration, error) {
+				return nil, errors.New("database unavailable")
+			},
+		},
+	}
+
+	code, body := post(t, server, url.Values{"section": {"configuration"}, "key_value": {"event_socket.conf"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `<?xml version="1.0" encoding="UTF-8"?>
+<document type="freeswitch/xml">
+  <section name="configuration">
+    <configuration name="event_socket.conf" description="Socket Client">
+      <settings>
+        <param name="listen-port" value="8021"></param>
+        <param name="password" value="ClueCon"></param>
+      </settings>
+    </configuration>
+  </section>
+</document>`, body)
+
+	code, body = post(t, server, url.Values{"section": {"configuration"}, "key_value": {"sofia.conf"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, testNotFound, body)
+
+	code, _ = post(t, server, url.Values{"section": {"configuration"}, "key_value": {"broken.conf"}})
+	assert.Equal(t, http.StatusInternalServerError, code)
+
+	code, body = post(t, server, url.Values{"section": {"phrases"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, testNotFound, body)
+}